@@ -6,38 +6,84 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/errgroup"
+
 	"github.com/vk74/evi/be/src/core/auth"
 	"github.com/vk74/evi/be/src/core/config"
 	"github.com/vk74/evi/be/src/core/database"
+	"github.com/vk74/evi/be/src/core/metrics"
 	"github.com/vk74/evi/be/src/core/middleware"
 	"github.com/vk74/evi/be/src/core/public"
+	"github.com/vk74/evi/be/src/core/valkey"
+	"github.com/vk74/evi/pkg/authkeys"
+	evilog "github.com/vk74/evi/pkg/log"
+	sharedmetrics "github.com/vk74/evi/pkg/metrics"
+	"github.com/vk74/evi/pkg/settings"
+)
+
+// version and commit are set via -ldflags at build time.
+var (
+	version = "dev"
+	commit  = "none"
 )
 
 func main() {
 	// Initialize configuration
 	cfg := config.Load()
 
+	// Initialize the shared slog logger and install it as the default
+	evilog.New(evilog.Config{Level: cfg.LogLevel, Format: cfg.LogFormat})
+
+	sharedmetrics.BuildInfo(prometheus.DefaultRegisterer, version, commit)
+
+	// Root context for the process: cancelled on SIGINT/SIGTERM, at which
+	// point every subsystem below starts draining.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize database connection
-	ctx, cancelDB := context.WithTimeout(context.Background(), 10*time.Second)
-	db, err := database.New(ctx, cfg.DatabaseURL)
-	cancelDB()
+	initCtx, cancelInit := context.WithTimeout(ctx, 10*time.Second)
+	db, err := database.New(initCtx, cfg.DatabaseURL)
 	if err != nil {
+		cancelInit()
 		slog.Error("Failed to connect to database", "error", err)
 		os.Exit(1)
 	}
 	defer db.Close()
+	metrics.RegisterDBPool(db.Pool)
+
+	valkeyClient, err := valkey.New(initCtx, cfg.ValkeyURL, cfg.ValkeyPassword)
+	cancelInit()
+	if err != nil {
+		slog.Error("Failed to connect to Valkey", "error", err)
+		os.Exit(1)
+	}
+	defer valkeyClient.Close()
+	sharedmetrics.InstrumentValkey(valkeyClient)
+
+	settingsStore := settings.NewStore(db.Pool, valkeyClient)
+
+	// draining is flipped to true the moment shutdown begins, so /api/health
+	// can fail fast and load balancers stop routing new traffic.
+	var draining atomic.Bool
 
 	// Initialize Echo router
 	e := echo.New()
 
 	// Core middleware
-	e.Use(echomiddleware.Logger())
 	e.Use(echomiddleware.Recover())
-	
+	e.Use(middleware.RequestLogger())
+	e.Use(metrics.Middleware())
+
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+
 	// CORS middleware configuration
 	e.Use(echomiddleware.CORSWithConfig(echomiddleware.CORSConfig{
 		AllowOrigins: []string{"http://localhost:8080", "http://localhost:3000"}, // Adjust for frontend
@@ -48,6 +94,12 @@ func main() {
 
 	// Public routes
 	e.GET("/api/health", func(c echo.Context) error {
+		if draining.Load() {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{
+				"status": "draining",
+				"engine": "go",
+			})
+		}
 		// Ping database to ensure health status is accurate
 		if err := db.Pool.Ping(c.Request().Context()); err != nil {
 			return c.JSON(http.StatusServiceUnavailable, map[string]string{
@@ -61,16 +113,25 @@ func main() {
 		})
 	})
 
-	authHandler := auth.NewHandler(db.Pool)
+	keyStore := authkeys.NewStore(db.Pool)
+	keyCache := authkeys.NewCache(keyStore)
+
+	authHandler := auth.NewHandler(db.Pool, keyStore)
 	e.POST("/api/auth/login", authHandler.Login)
+	e.POST("/api/auth/refresh", authHandler.Refresh)
+	e.POST("/api/auth/logout", authHandler.Logout)
+
+	jwksHandler := auth.NewJWKSHandler(keyStore)
+	e.GET("/.well-known/jwks.json", jwksHandler.ServeJWKS)
 
-	publicHandler := public.NewHandler(db.Pool)
+	publicHandler := public.NewHandler(settingsStore)
 	e.GET("/api/public/settings", publicHandler.FetchPublicSettings)
+	e.GET("/api/public/settings/:section_path", publicHandler.FetchSection)
 
 	// Protected routes group (requires JWT)
 	protected := e.Group("/api/protected")
-	protected.Use(middleware.Auth(cfg.JWTSecret))
-	
+	protected.Use(middleware.Auth(keyCache))
+
 	protected.GET("/profile", func(c echo.Context) error {
 		userID := c.Get("user_id")
 		return c.JSON(http.StatusOK, map[string]interface{}{
@@ -79,24 +140,36 @@ func main() {
 		})
 	})
 
-	// Start server in a goroutine for graceful shutdown
-	go func() {
+	// Each subsystem below is its own errgroup member: it returns when ctx
+	// is cancelled and its error (if any) is what determines main's exit
+	// status.
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
 		slog.Info("Starting Go backend", "port", cfg.APIPort)
 		if err := e.Start(":" + cfg.APIPort); err != nil && err != http.ErrServerClosed {
-			e.Logger.Fatal("Shutting down the server")
+			return err
 		}
-	}()
+		return nil
+	})
+
+	g.Go(func() error {
+		<-gctx.Done()
+		slog.Info("Shutting down gracefully...", "timeout", cfg.ShutdownTimeout)
+		draining.Store(true)
 
-	// Graceful Shutdown on SIGINT or SIGTERM
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		return e.Shutdown(shutdownCtx)
+	})
 
-	ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancelShutdown()
+	g.Go(func() error {
+		return settingsStore.Subscribe(gctx)
+	})
 
-	slog.Info("Shutting down gracefully...")
-	if err := e.Shutdown(ctxShutdown); err != nil {
-		e.Logger.Fatal(err)
+	if err := g.Wait(); err != nil {
+		slog.Error("evi-backend stopped with error", "error", err)
+		os.Exit(1)
 	}
+	slog.Info("evi-backend stopped")
 }
@@ -2,23 +2,38 @@ package auth
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/vk74/evi/pkg/authkeys"
 )
 
+// dbHandle is the slice of *pgxpool.Pool this package actually calls,
+// narrowed to an interface so tests can exercise Handler's rotation and
+// reuse-detection logic against a fake instead of a live Postgres.
+type dbHandle interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type LoginRequest struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
-	// Additional fields like deviceFingerprint can be added here
+	Username          string `json:"username" validate:"required"`
+	Password          string `json:"password" validate:"required"`
+	DeviceFingerprint string `json:"device_fingerprint,omitempty"`
 }
 
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	User    *User  `json:"user,omitempty"`
+	Success bool       `json:"success"`
+	Message string     `json:"message,omitempty"`
+	User    *User      `json:"user,omitempty"`
+	Tokens  *TokenPair `json:"tokens,omitempty"`
 }
 
 type User struct {
@@ -27,11 +42,12 @@ type User struct {
 }
 
 type Handler struct {
-	DB *pgxpool.Pool
+	DB   dbHandle
+	Keys *authkeys.Store
 }
 
-func NewHandler(db *pgxpool.Pool) *Handler {
-	return &Handler{DB: db}
+func NewHandler(db *pgxpool.Pool, keys *authkeys.Store) *Handler {
+	return &Handler{DB: db, Keys: keys}
 }
 
 func (h *Handler) Login(c echo.Context) error {
@@ -51,11 +67,11 @@ func (h *Handler) Login(c echo.Context) error {
 	}
 
 	// Fetch user from DB
-	ctx := context.Background()
+	ctx := c.Request().Context()
 	var userID, hashedPassword, accountStatus string
-	
-	err := h.DB.QueryRow(ctx, 
-		"SELECT user_id, hashed_password, account_status FROM app.users WHERE username = $1", 
+
+	err := h.DB.QueryRow(ctx,
+		"SELECT user_id, hashed_password, account_status FROM app.users WHERE username = $1",
 		req.Username,
 	).Scan(&userID, &hashedPassword, &accountStatus)
 
@@ -84,13 +100,123 @@ func (h *Handler) Login(c echo.Context) error {
 		})
 	}
 
-	// Password is correct!
+	tokens, err := h.issueTokenPair(ctx, userID, req.DeviceFingerprint)
+	if err != nil {
+		slog.Error("login: failed to issue tokens", "error", err)
+		return c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Message: "Failed to issue tokens",
+		})
+	}
+
 	return c.JSON(http.StatusOK, LoginResponse{
 		Success: true,
 		User: &User{
 			Username: req.Username,
 			UUID:     userID,
 		},
-		// Tokens will be issued here in future implementation
+		Tokens: tokens,
+	})
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type RefreshResponse struct {
+	Success bool       `json:"success"`
+	Message string     `json:"message,omitempty"`
+	Tokens  *TokenPair `json:"tokens,omitempty"`
+}
+
+// Refresh rotates a refresh token: the presented token is consumed and a
+// new access/refresh pair is issued in the same family. Presenting a
+// refresh token a second time is treated as reuse of a stolen token and
+// revokes the entire family.
+func (h *Handler) Refresh(c echo.Context) error {
+	var req RefreshRequest
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, RefreshResponse{
+			Success: false,
+			Message: "Invalid request payload",
+		})
+	}
+
+	ctx := c.Request().Context()
+	claimed, err := h.claimRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		slog.Error("refresh: claim failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, RefreshResponse{Success: false, Message: "Internal error"})
+	}
+	if claimed == nil {
+		// The token either never existed, or it was already revoked by
+		// an earlier claim (a concurrent retry of this same request, or
+		// a replayed/stolen token). Look it up, ignoring revocation, to
+		// tell those two cases apart: only the latter is reuse and
+		// revokes the family.
+		row, err := h.lookupRefreshToken(ctx, req.RefreshToken)
+		if err != nil {
+			slog.Error("refresh: lookup failed", "error", err)
+			return c.JSON(http.StatusInternalServerError, RefreshResponse{Success: false, Message: "Internal error"})
+		}
+		if row != nil {
+			if err := h.revokeFamily(ctx, row.FamilyID); err != nil {
+				slog.Error("refresh: failed to revoke token family on reuse detection", "error", err)
+			}
+			return c.JSON(http.StatusUnauthorized, RefreshResponse{Success: false, Message: "Refresh token reuse detected, session revoked"})
+		}
+		return c.JSON(http.StatusUnauthorized, RefreshResponse{Success: false, Message: "Invalid or expired refresh token"})
+	}
+	if claimed.ExpiresAt.Before(time.Now()) {
+		return c.JSON(http.StatusUnauthorized, RefreshResponse{Success: false, Message: "Invalid or expired refresh token"})
+	}
+
+	access, err := h.mintAccessToken(ctx, claimed.UserID)
+	if err != nil {
+		slog.Error("refresh: failed to mint access token", "error", err)
+		return c.JSON(http.StatusInternalServerError, RefreshResponse{Success: false, Message: "Internal error"})
+	}
+	newRefresh, err := h.issueRefreshToken(ctx, claimed.UserID, claimed.FamilyID, "")
+	if err != nil {
+		slog.Error("refresh: failed to issue refresh token", "error", err)
+		return c.JSON(http.StatusInternalServerError, RefreshResponse{Success: false, Message: "Internal error"})
+	}
+
+	return c.JSON(http.StatusOK, RefreshResponse{
+		Success: true,
+		Tokens:  &TokenPair{AccessToken: access, RefreshToken: newRefresh, ExpiresIn: int(authkeys.AccessTokenTTL.Seconds())},
 	})
 }
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// Logout revokes the entire refresh-token family the presented token
+// belongs to, ending every session descended from that login.
+func (h *Handler) Logout(c echo.Context) error {
+	var req LogoutRequest
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, LogoutResponse{Success: false, Message: "Invalid request payload"})
+	}
+
+	ctx := c.Request().Context()
+	row, err := h.lookupRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		slog.Error("logout: lookup failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, LogoutResponse{Success: false, Message: "Internal error"})
+	}
+	if row != nil {
+		if err := h.revokeFamily(ctx, row.FamilyID); err != nil {
+			slog.Error("logout: failed to revoke token family", "error", err)
+			return c.JSON(http.StatusInternalServerError, LogoutResponse{Success: false, Message: "Internal error"})
+		}
+	}
+
+	return c.JSON(http.StatusOK, LogoutResponse{Success: true})
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+// fakeRefreshToken mirrors one row of app.refresh_tokens.
+type fakeRefreshToken struct {
+	userID    string
+	familyID  string
+	expiresAt time.Time
+	revokedAt *time.Time
+}
+
+// fakeDB is a minimal in-memory stand-in for dbHandle, just enough to drive
+// the handful of queries this package issues against app.refresh_tokens.
+type fakeDB struct {
+	mu     sync.Mutex
+	tokens map[string]*fakeRefreshToken // keyed by hash
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{tokens: map[string]*fakeRefreshToken{}}
+}
+
+func (f *fakeDB) seed(raw, userID, familyID string, expiresAt time.Time, revoked bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var revokedAt *time.Time
+	if revoked {
+		t := time.Now()
+		revokedAt = &t
+	}
+	f.tokens[hashToken(raw)] = &fakeRefreshToken{userID: userID, familyID: familyID, expiresAt: expiresAt, revokedAt: revokedAt}
+}
+
+func (f *fakeDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.Contains(sql, "INSERT INTO app.refresh_tokens"):
+		userID, _ := args[0].(string)
+		familyID, _ := args[1].(string)
+		hash, _ := args[2].(string)
+		expiresAt, _ := args[3].(time.Time)
+		f.tokens[hash] = &fakeRefreshToken{userID: userID, familyID: familyID, expiresAt: expiresAt}
+		return pgconn.NewCommandTag("INSERT 0 1"), nil
+	case strings.Contains(sql, "WHERE family_id = $1"):
+		familyID, _ := args[0].(string)
+		now := time.Now()
+		for _, t := range f.tokens {
+			if t.familyID == familyID && t.revokedAt == nil {
+				t.revokedAt = &now
+			}
+		}
+		return pgconn.NewCommandTag("UPDATE"), nil
+	}
+	return pgconn.CommandTag{}, fmt.Errorf("fakeDB: unhandled Exec: %s", sql)
+}
+
+func (f *fakeDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.Contains(sql, "RETURNING user_id, family_id, expires_at"):
+		hash, _ := args[0].(string)
+		t, ok := f.tokens[hash]
+		if !ok || t.revokedAt != nil {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+		now := time.Now()
+		t.revokedAt = &now
+		return fakeRow{vals: []any{t.userID, t.familyID, t.expiresAt}}
+	case strings.Contains(sql, "SELECT user_id, family_id, expires_at, revoked_at"):
+		hash, _ := args[0].(string)
+		t, ok := f.tokens[hash]
+		if !ok {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+		return fakeRow{vals: []any{t.userID, t.familyID, t.expiresAt, t.revokedAt}}
+	}
+	return fakeRow{err: fmt.Errorf("fakeDB: unhandled QueryRow: %s", sql)}
+}
+
+type fakeRow struct {
+	vals []any
+	err  error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	for i, d := range dest {
+		switch dp := d.(type) {
+		case *string:
+			*dp = r.vals[i].(string)
+		case *time.Time:
+			*dp = r.vals[i].(time.Time)
+		case **time.Time:
+			*dp = r.vals[i].(*time.Time)
+		default:
+			return fmt.Errorf("fakeRow: unsupported dest type %T", d)
+		}
+	}
+	return nil
+}
+
+func refreshRequest(t *testing.T, h *Handler, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	body, err := json.Marshal(RefreshRequest{RefreshToken: token})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	req := httptest.NewRequest(echo.POST, "/api/auth/refresh", strings.NewReader(string(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := h.Refresh(c); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	return rec
+}
+
+// TestRefresh_ReuseRevokesFamily verifies that presenting a refresh token
+// that was already claimed once - i.e. replaying it, whether that's an
+// attacker reusing a stolen token or a second concurrent caller that lost
+// the race in claimRefreshToken - revokes every other token in the same
+// family, not just the one being replayed.
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	db := newFakeDB()
+	h := &Handler{DB: db}
+
+	const familyID = "family-1"
+	const replayed = "already-used-refresh-token"
+	const sibling = "sibling-refresh-token"
+	// revoked=true simulates the state claimRefreshToken leaves behind
+	// after its first, legitimate use.
+	db.seed(replayed, "user-1", familyID, time.Now().Add(time.Hour), true)
+	db.seed(sibling, "user-1", familyID, time.Now().Add(time.Hour), false)
+
+	rec := refreshRequest(t, h, replayed)
+	if rec.Code != 401 {
+		t.Fatalf("replayed refresh: want 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp RefreshResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("replayed refresh token was accepted")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.tokens[hashToken(sibling)].revokedAt == nil {
+		t.Fatal("reuse detection did not revoke sibling token in the same family")
+	}
+}
+
+// TestRefresh_ExpiredTokenRejected verifies that a token which has expired,
+// but was never explicitly revoked, is still rejected rather than honored.
+func TestRefresh_ExpiredTokenRejected(t *testing.T) {
+	db := newFakeDB()
+	h := &Handler{DB: db}
+
+	const token = "expired-refresh-token"
+	db.seed(token, "user-1", "family-1", time.Now().Add(-time.Hour), false)
+
+	rec := refreshRequest(t, h, token)
+	if rec.Code != 401 {
+		t.Fatalf("expired refresh: want 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp RefreshResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("expired token was accepted")
+	}
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/vk74/evi/pkg/authkeys"
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwksResponse struct {
+	Keys []jwk `json:"keys"`
+}
+
+// JWKSHandler serves the published public half of the signing-key store.
+type JWKSHandler struct {
+	Keys *authkeys.Store
+}
+
+func NewJWKSHandler(keys *authkeys.Store) *JWKSHandler {
+	return &JWKSHandler{Keys: keys}
+}
+
+// ServeJWKS handles GET /.well-known/jwks.json.
+func (h *JWKSHandler) ServeJWKS(c echo.Context) error {
+	publicKeys, err := h.Keys.AllPublicKeys(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, jwksResponse{Keys: []jwk{}})
+	}
+
+	resp := jwksResponse{Keys: make([]jwk, 0, len(publicKeys))}
+	for kid, pub := range publicKeys {
+		resp.Keys = append(resp.Keys, toJWK(kid, pub))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func toJWK(kid string, pub *rsa.PublicKey) jwk {
+	return jwk{
+		Kty: "RSA",
+		Use: "sig",
+		Kid: kid,
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
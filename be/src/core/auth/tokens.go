@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/vk74/evi/pkg/authkeys"
+)
+
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// TokenPair is returned to the client on login and on refresh.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// mintAccessToken signs a short-lived RS256 JWT for userID using the
+// currently active signing key, tagging it with that key's kid.
+func (h *Handler) mintAccessToken(ctx context.Context, userID string) (string, error) {
+	key, err := h.Keys.Active(ctx)
+	if err != nil {
+		return "", fmt.Errorf("mint access token: %w", err)
+	}
+
+	claims := jwt.RegisteredClaims{
+		Subject:   userID,
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(authkeys.AccessTokenTTL)),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.Kid
+
+	signed, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("sign access token: %w", err)
+	}
+	return signed, nil
+}
+
+// issueRefreshToken generates a new opaque refresh token within familyID,
+// stores only its hash, and returns the raw token for the client to hold.
+func (h *Handler) issueRefreshToken(ctx context.Context, userID, familyID, deviceFingerprint string) (string, error) {
+	raw, err := randomToken()
+	if err != nil {
+		return "", fmt.Errorf("generate refresh token: %w", err)
+	}
+
+	_, err = h.DB.Exec(ctx, `
+		INSERT INTO app.refresh_tokens (user_id, family_id, hash, expires_at, device_fingerprint)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))`,
+		userID, familyID, hashToken(raw), time.Now().Add(refreshTokenTTL), deviceFingerprint,
+	)
+	if err != nil {
+		return "", fmt.Errorf("store refresh token: %w", err)
+	}
+	return raw, nil
+}
+
+// issueTokenPair mints a fresh access token and starts a new refresh-token
+// family, used on login.
+func (h *Handler) issueTokenPair(ctx context.Context, userID, deviceFingerprint string) (*TokenPair, error) {
+	access, err := h.mintAccessToken(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := h.issueRefreshToken(ctx, userID, uuid.NewString(), deviceFingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresIn: int(authkeys.AccessTokenTTL.Seconds())}, nil
+}
+
+type refreshTokenRow struct {
+	UserID    string
+	FamilyID  string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+func (h *Handler) lookupRefreshToken(ctx context.Context, raw string) (*refreshTokenRow, error) {
+	var row refreshTokenRow
+	err := h.DB.QueryRow(ctx, `
+		SELECT user_id, family_id, expires_at, revoked_at
+		FROM app.refresh_tokens WHERE hash = $1`, hashToken(raw),
+	).Scan(&row.UserID, &row.FamilyID, &row.ExpiresAt, &row.RevokedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup refresh token: %w", err)
+	}
+	return &row, nil
+}
+
+// claimRefreshToken atomically revokes the token identified by raw,
+// provided it hasn't been revoked already, and returns the row it
+// matched. The revoke-and-read happen in a single statement so that two
+// concurrent callers presenting the same still-valid token (a retried
+// request, or a replayed/stolen token) can never both succeed: exactly
+// one claims the row, the other gets nil and must treat it as reuse.
+func (h *Handler) claimRefreshToken(ctx context.Context, raw string) (*refreshTokenRow, error) {
+	var row refreshTokenRow
+	err := h.DB.QueryRow(ctx, `
+		UPDATE app.refresh_tokens SET revoked_at = now()
+		WHERE hash = $1 AND revoked_at IS NULL
+		RETURNING user_id, family_id, expires_at`, hashToken(raw),
+	).Scan(&row.UserID, &row.FamilyID, &row.ExpiresAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim refresh token: %w", err)
+	}
+	return &row, nil
+}
+
+// revokeFamily revokes every token descended from the same login, used for
+// both normal logout and reuse-detection lockout.
+func (h *Handler) revokeFamily(ctx context.Context, familyID string) error {
+	_, err := h.DB.Exec(ctx, `UPDATE app.refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	return err
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,14 +3,23 @@ package config
 import (
 	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	APIPort     string
-	DatabaseURL string
-	JWTSecret   string
+	APIPort        string
+	DatabaseURL    string
+	ValkeyURL      string
+	ValkeyPassword string
+	LogLevel       string
+	LogFormat      string
+	// ShutdownTimeout bounds how long graceful shutdown waits for
+	// in-flight requests to finish before forcing the server and pools
+	// closed.
+	ShutdownTimeout time.Duration
 }
 
 // Load reads configuration from .env file and environment variables
@@ -21,9 +30,13 @@ func Load() *Config {
 	}
 
 	cfg := &Config{
-		APIPort:     getEnv("API_PORT", "7777"),
-		DatabaseURL: getEnv("DATABASE_URL", "postgresql://app_service:P@ssw0rd@localhost:5445/maindb"),
-		JWTSecret:   getEnv("JWT_PRIVATE_KEY", "your-super-secret-jwt-key"), // default for local dev
+		APIPort:         getEnv("API_PORT", "7777"),
+		DatabaseURL:     getEnv("DATABASE_URL", "postgresql://app_service:P@ssw0rd@localhost:5445/maindb"),
+		ValkeyURL:       getEnv("VALKEY_URL", "localhost:6379"),
+		ValkeyPassword:  getEnv("VALKEY_PASSWORD", ""),
+		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		LogFormat:       getEnv("LOG_FORMAT", "pretty"),
+		ShutdownTimeout: time.Duration(getEnvInt("SHUTDOWN_TIMEOUT", 30)) * time.Second,
 	}
 
 	return cfg
@@ -35,3 +48,12 @@ func getEnv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+func getEnvInt(key string, defaultVal int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultVal
+}
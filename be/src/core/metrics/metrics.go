@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	sharedmetrics "github.com/vk74/evi/pkg/metrics"
+)
+
+var httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "http_request_duration_seconds",
+	Help: "HTTP request latency in seconds.",
+}, []string{"route", "method", "status"})
+
+func init() {
+	prometheus.MustRegister(httpRequestDuration)
+}
+
+// Middleware records http_request_duration_seconds for every request,
+// labeled by route/method/status.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			httpRequestDuration.WithLabelValues(c.Path(), c.Request().Method, strconv.Itoa(c.Response().Status)).
+				Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// RegisterDBPool registers a collector that exports pool.Stat() on every
+// /metrics scrape.
+func RegisterDBPool(pool *pgxpool.Pool) {
+	prometheus.MustRegister(sharedmetrics.NewDBPoolCollector(pool))
+}
+
+// Handler returns the promhttp handler to mount at /metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -7,10 +7,14 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+
+	"github.com/vk74/evi/pkg/authkeys"
 )
 
-// Auth is a middleware that checks for a valid JWT token in the Authorization header.
-func Auth(secretKey string) echo.MiddlewareFunc {
+// Auth is a middleware that checks for a valid RS256 access token in the
+// Authorization header, verifying it against the kid-indexed signing-key
+// cache (refreshed from PostgreSQL on an unknown kid).
+func Auth(keys *authkeys.Cache) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
@@ -28,12 +32,14 @@ func Auth(secretKey string) echo.MiddlewareFunc {
 
 			// Parse and validate the token
 			token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-				// Don't forget to validate the alg is what you expect
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok { // Or RSA if you use RS256
+				if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
 					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 				}
-				// Return the secret key used to sign the token
-				return []byte(secretKey), nil
+				kid, ok := token.Header["kid"].(string)
+				if !ok {
+					return nil, fmt.Errorf("token missing kid header")
+				}
+				return keys.PublicKey(c.Request().Context(), kid)
 			})
 
 			if err != nil || !token.Valid {
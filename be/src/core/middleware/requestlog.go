@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/vk74/evi/pkg/log"
+)
+
+// RequestIDHeader is the header used to propagate/generate request correlation IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestLogger generates or propagates an X-Request-ID header, stores it
+// on the request context so downstream code (handlers, DB queries) can pick
+// it up, and emits one structured access log line per request.
+func RequestLogger() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			reqID := c.Request().Header.Get(RequestIDHeader)
+			if reqID == "" {
+				reqID = uuid.NewString()
+			}
+			c.Response().Header().Set(RequestIDHeader, reqID)
+			c.SetRequest(c.Request().WithContext(log.WithRequestID(c.Request().Context(), reqID)))
+
+			start := time.Now()
+			err := next(c)
+
+			slog.Info("request",
+				"method", c.Request().Method,
+				"path", c.Path(),
+				"status", c.Response().Status,
+				"latency_ms", time.Since(start).Milliseconds(),
+				"user_id", c.Get("user_id"),
+				"request_id", reqID,
+			)
+
+			return err
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package public
 
 import (
-	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
+
+	"github.com/vk74/evi/pkg/settings"
 )
 
 type PublicSetting struct {
@@ -21,22 +24,25 @@ type PublicSettingsResponse struct {
 	Error    string          `json:"error,omitempty"`
 }
 
+type SectionSettingsResponse struct {
+	Success     bool            `json:"success"`
+	SectionPath string          `json:"section_path,omitempty"`
+	Settings    []PublicSetting `json:"settings"`
+	Error       string          `json:"error,omitempty"`
+}
+
 type Handler struct {
-	DB *pgxpool.Pool
+	Settings *settings.Store
 }
 
-func NewHandler(db *pgxpool.Pool) *Handler {
-	return &Handler{DB: db}
+func NewHandler(store *settings.Store) *Handler {
+	return &Handler{Settings: store}
 }
 
+// FetchPublicSettings handles GET /api/public/settings, serving every
+// public, non-confidential setting from the cached settings.Store.
 func (h *Handler) FetchPublicSettings(c echo.Context) error {
-	ctx := context.Background()
-	
-	// Query app.app_settings for public and non-confidential settings
-	rows, err := h.DB.Query(ctx, 
-		`SELECT section_path, setting_name, value 
-		 FROM app.app_settings 
-		 WHERE is_public = true AND confidentiality = false`)
+	all, err := h.Settings.All(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, PublicSettingsResponse{
 			Success:  false,
@@ -44,17 +50,15 @@ func (h *Handler) FetchPublicSettings(c echo.Context) error {
 			Error:    "Failed to fetch public settings",
 		})
 	}
-	defer rows.Close()
 
-	settings := make([]PublicSetting, 0)
-	for rows.Next() {
-		var s PublicSetting
-		err := rows.Scan(&s.SectionPath, &s.SettingName, &s.Value)
-		if err != nil {
-			continue
-		}
-		s.IsPublic = true
-		settings = append(settings, s)
+	settings := make([]PublicSetting, 0, len(all))
+	for _, s := range all {
+		settings = append(settings, PublicSetting{
+			SectionPath: s.SectionPath,
+			SettingName: s.SettingName,
+			Value:       s.Value,
+			IsPublic:    s.IsPublic,
+		})
 	}
 
 	return c.JSON(http.StatusOK, PublicSettingsResponse{
@@ -62,3 +66,47 @@ func (h *Handler) FetchPublicSettings(c echo.Context) error {
 		Settings: settings,
 	})
 }
+
+// FetchSection handles GET /api/public/settings/:section_path, serving
+// public settings for a single section from the cached settings.Store and
+// supporting If-None-Match so frontends can cheaply poll for changes.
+func (h *Handler) FetchSection(c echo.Context) error {
+	sectionPath := c.Param("section_path")
+
+	section, err := h.Settings.Section(c.Request().Context(), sectionPath)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, SectionSettingsResponse{
+			Success: false,
+			Error:   "Failed to fetch settings",
+		})
+	}
+
+	publicSettings := make([]PublicSetting, 0, len(section))
+	for _, s := range section {
+		if !s.IsPublic || s.Confidentiality {
+			continue
+		}
+		publicSettings = append(publicSettings, PublicSetting{
+			SectionPath: s.SectionPath,
+			SettingName: s.SettingName,
+			Value:       s.Value,
+			IsPublic:    s.IsPublic,
+		})
+	}
+
+	resp := SectionSettingsResponse{Success: true, SectionPath: sectionPath, Settings: publicSettings}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, SectionSettingsResponse{Success: false, Error: "Failed to encode settings"})
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	c.Response().Header().Set("ETag", etag)
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSONBlob(http.StatusOK, body)
+}
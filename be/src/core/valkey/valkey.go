@@ -0,0 +1,29 @@
+package valkey
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// New creates a go-redis/v9 client and verifies connectivity via PING.
+// addr format: "host:port" (e.g. "localhost:6379").
+func New(ctx context.Context, addr, password string) (*redis.Client, error) {
+	slog.Info("Connecting to Valkey...", "addr", addr)
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       0,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("ping valkey: %w", err)
+	}
+
+	slog.Info("Valkey connected")
+	return client, nil
+}
@@ -0,0 +1,47 @@
+// Version: 1.0.0
+// Purpose: In-memory cache of signing-key public keys, keyed by kid.
+//          Unknown kids trigger a single Store lookup and are cached from
+//          then on, so steady-state token verification never touches
+//          PostgreSQL.
+// Backend file: cache.go
+
+package authkeys
+
+import (
+	"context"
+	"crypto/rsa"
+	"sync"
+)
+
+// Cache serves public keys by kid, backed by a Store for cache misses.
+type Cache struct {
+	store *Store
+	mu    sync.RWMutex
+	keys  map[string]*rsa.PublicKey
+}
+
+// NewCache creates a Cache backed by store.
+func NewCache(store *Store) *Cache {
+	return &Cache{store: store, keys: make(map[string]*rsa.PublicKey)}
+}
+
+// PublicKey returns the public key for kid, refreshing from the backing
+// Store on a cache miss.
+func (c *Cache) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	c.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	key, err := c.store.PublicKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.keys[kid] = key
+	c.mu.Unlock()
+	return key, nil
+}
@@ -0,0 +1,174 @@
+// Version: 1.0.0
+// Purpose: RSA signing-key storage and rotation for RS256 access tokens,
+//          shared by evi-backend (signs and verifies tokens) and evi-worker
+//          (runs the rotation job). Keys are kid-indexed rows in
+//          app.signing_keys; the newest non-retired row is "active".
+// Backend file: store.go
+
+package authkeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const rsaKeyBits = 2048
+
+// AccessTokenTTL is how long a minted access token stays valid. Retired
+// signing keys must remain verifiable for at least this long, since a
+// token signed just before rotation is still valid after it.
+const AccessTokenTTL = 15 * time.Minute
+
+// SigningKey is one RSA keypair persisted in app.signing_keys.
+type SigningKey struct {
+	Kid        string
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+	CreatedAt  time.Time
+	RetiredAt  *time.Time
+}
+
+// Store persists and retrieves signing keys from PostgreSQL.
+type Store struct {
+	db *pgxpool.Pool
+}
+
+// NewStore creates a Store backed by the given connection pool.
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{db: db}
+}
+
+// Active returns the newest non-retired key, generating and persisting one
+// if none exists yet.
+func (s *Store) Active(ctx context.Context) (*SigningKey, error) {
+	key, err := s.queryActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		return key, nil
+	}
+	return s.Generate(ctx)
+}
+
+func (s *Store) queryActive(ctx context.Context) (*SigningKey, error) {
+	var kid string
+	var der []byte
+	var createdAt time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT kid, private_key_der, created_at
+		FROM app.signing_keys
+		WHERE retired_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1`).Scan(&kid, &der, &createdAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query active signing key: %w", err)
+	}
+
+	priv, err := x509.ParsePKCS1PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key %q: %w", kid, err)
+	}
+	return &SigningKey{Kid: kid, PrivateKey: priv, PublicKey: &priv.PublicKey, CreatedAt: createdAt}, nil
+}
+
+// Generate creates a new RSA keypair, persists it, and returns it. It does
+// not retire any existing key; callers that want a clean rotation should
+// follow up with RetireExpired.
+func (s *Store) Generate(ctx context.Context) (*SigningKey, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generate signing key: %w", err)
+	}
+
+	kid := uuid.NewString()
+	der := x509.MarshalPKCS1PrivateKey(priv)
+
+	var createdAt time.Time
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO app.signing_keys (kid, private_key_der)
+		VALUES ($1, $2)
+		RETURNING created_at`, kid, der).Scan(&createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert signing key: %w", err)
+	}
+
+	return &SigningKey{Kid: kid, PrivateKey: priv, PublicKey: &priv.PublicKey, CreatedAt: createdAt}, nil
+}
+
+// PublicKey looks up the public key for kid regardless of retirement
+// status, since a retired key must stay verifiable until every token it
+// signed has expired.
+func (s *Store) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	var der []byte
+	err := s.db.QueryRow(ctx, `SELECT private_key_der FROM app.signing_keys WHERE kid = $1`, kid).Scan(&der)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("unknown signing key kid %q", kid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query signing key %q: %w", kid, err)
+	}
+
+	priv, err := x509.ParsePKCS1PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key %q: %w", kid, err)
+	}
+	return &priv.PublicKey, nil
+}
+
+// AllPublicKeys returns every key that is still active, plus any retired
+// within the last AccessTokenTTL so tokens they signed remain verifiable
+// via JWKS while caches catch up.
+func (s *Store) AllPublicKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT kid, private_key_der FROM app.signing_keys
+		WHERE retired_at IS NULL OR retired_at > now() - ($1 || ' seconds')::interval`,
+		int(AccessTokenTTL.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("query signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make(map[string]*rsa.PublicKey)
+	for rows.Next() {
+		var kid string
+		var der []byte
+		if err := rows.Scan(&kid, &der); err != nil {
+			return nil, fmt.Errorf("scan signing key: %w", err)
+		}
+		priv, err := x509.ParsePKCS1PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("parse signing key %q: %w", kid, err)
+		}
+		keys[kid] = &priv.PublicKey
+	}
+	return keys, nil
+}
+
+// RetireExpired marks every key older than olderThan as retired, except
+// whichever key is currently newest, so a rotation never retires the key
+// it just generated.
+func (s *Store) RetireExpired(ctx context.Context, olderThan time.Duration) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE app.signing_keys
+		SET retired_at = now()
+		WHERE retired_at IS NULL
+		  AND created_at < now() - ($1 || ' seconds')::interval
+		  AND kid NOT IN (SELECT kid FROM app.signing_keys ORDER BY created_at DESC LIMIT 1)`,
+		int(olderThan.Seconds()))
+	if err != nil {
+		return fmt.Errorf("retire expired signing keys: %w", err)
+	}
+	return nil
+}
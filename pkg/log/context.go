@@ -0,0 +1,37 @@
+// Version: 1.0.0
+// Purpose: Typed context keys for threading correlation IDs (request_id,
+//          job_id) through handler, poller, and DB-query logging.
+// Backend file: context.go
+
+package log
+
+import "context"
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	jobIDKey
+)
+
+// WithRequestID returns a copy of ctx carrying requestID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or "" if none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithJobID returns a copy of ctx carrying jobID.
+func WithJobID(ctx context.Context, jobID string) context.Context {
+	return context.WithValue(ctx, jobIDKey, jobID)
+}
+
+// JobIDFromContext returns the job ID stored in ctx, or "" if none.
+func JobIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(jobIDKey).(string)
+	return id
+}
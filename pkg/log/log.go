@@ -0,0 +1,54 @@
+// Version: 1.0.0
+// Purpose: Shared slog-based logger construction for evi-backend and
+//          evi-worker, replacing the previous zerolog (worker) and
+//          hand-rolled slog setup (backend) with one implementation.
+// Backend file: log.go
+
+package log
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Config controls logger construction.
+type Config struct {
+	// Level is parsed case-insensitively ("debug", "info", "warn", "error").
+	// An unrecognized or empty value defaults to "info".
+	Level string
+	// Format selects the handler: "json" for structured output suited to
+	// log collectors, anything else (including empty) for human-readable
+	// text suited to dev terminals.
+	Format string
+}
+
+// New builds a *slog.Logger per cfg and installs it as the process-wide
+// default so packages that call the top-level slog functions pick it up.
+func New(cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(cfg.Level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(cfg.Format, "json") {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	return logger
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,72 @@
+// Version: 1.0.0
+// Purpose: Shared Prometheus collectors used by evi-backend and evi-worker:
+//          a build_info gauge and a pgxpool.Pool.Stat() adapter.
+// Backend file: dbpool.go
+
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BuildInfo registers a build_info gauge set to 1, labeled with version and
+// commit (normally injected via -ldflags at build time).
+func BuildInfo(reg prometheus.Registerer, version, commit string) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "build_info",
+		Help:        "Build metadata for the running binary. Always 1.",
+		ConstLabels: prometheus.Labels{"version": version, "commit": commit},
+	})
+	gauge.Set(1)
+	reg.MustRegister(gauge)
+}
+
+// dbPoolCollector exports pgxpool.Pool.Stat() as Prometheus gauges/counters.
+type dbPoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	newConnsTotal   *prometheus.Desc
+	acquireTotal    *prometheus.Desc
+	acquireDuration *prometheus.Desc
+}
+
+// NewDBPoolCollector creates a prometheus.Collector that reads
+// pool.Stat() fresh on every scrape.
+func NewDBPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	return &dbPoolCollector{
+		pool:            pool,
+		acquiredConns:   prometheus.NewDesc("db_pool_acquired_conns", "Connections currently acquired.", nil, nil),
+		idleConns:       prometheus.NewDesc("db_pool_idle_conns", "Connections idle in the pool.", nil, nil),
+		totalConns:      prometheus.NewDesc("db_pool_total_conns", "Total connections, idle or acquired.", nil, nil),
+		maxConns:        prometheus.NewDesc("db_pool_max_conns", "Configured maximum pool size.", nil, nil),
+		newConnsTotal:   prometheus.NewDesc("db_pool_new_conns_total", "Cumulative connections established.", nil, nil),
+		acquireTotal:    prometheus.NewDesc("db_pool_acquire_total", "Cumulative successful acquires.", nil, nil),
+		acquireDuration: prometheus.NewDesc("db_pool_acquire_duration_seconds_total", "Cumulative time spent waiting to acquire a connection.", nil, nil),
+	}
+}
+
+func (c *dbPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.newConnsTotal
+	ch <- c.acquireTotal
+	ch <- c.acquireDuration
+}
+
+func (c *dbPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.newConnsTotal, prometheus.CounterValue, float64(stat.NewConnsCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireTotal, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
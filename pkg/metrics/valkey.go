@@ -0,0 +1,46 @@
+// Version: 1.0.0
+// Purpose: go-redis hook that records Valkey command round-trip latency as
+//          a Prometheus histogram, shared by evi-backend and evi-worker.
+// Backend file: valkey.go
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+var valkeyCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "valkey_command_duration_seconds",
+	Help: "Valkey/Redis command round-trip latency in seconds.",
+}, []string{"command"})
+
+func init() {
+	prometheus.MustRegister(valkeyCommandDuration)
+}
+
+// InstrumentValkey attaches a hook to client that records
+// valkey_command_duration_seconds for every command it issues.
+func InstrumentValkey(client *redis.Client) {
+	client.AddHook(valkeyHook{})
+}
+
+type valkeyHook struct{}
+
+func (valkeyHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (valkeyHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		valkeyCommandDuration.WithLabelValues(cmd.Name()).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func (valkeyHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
@@ -0,0 +1,256 @@
+// Version: 1.0.0
+// Purpose: App-settings cache shared by evi-backend and evi-worker. Reads
+//          through PostgreSQL (app.app_settings) on a cache miss and is
+//          wired to stay consistent across instances via Valkey pub/sub
+//          invalidation (see subscribe.go and Invalidate) the moment
+//          something calls Invalidate on a mutation path; until then,
+//          cache coherence across instances relies on defaultTTL alone.
+// Backend file: store.go
+
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultTTL bounds how long a cached section is trusted between
+// invalidations, as a backstop against a missed pub/sub message.
+const defaultTTL = 30 * time.Second
+
+// dbHandle is the one *pgxpool.Pool method Store actually calls, narrowed
+// to an interface so tests can exercise the cache/TTL/singleflight
+// interplay against a fake instead of a live Postgres.
+type dbHandle interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Setting is one row of app.app_settings.
+type Setting struct {
+	SectionPath     string      `json:"section_path"`
+	SettingName     string      `json:"setting_name"`
+	Value           interface{} `json:"value"`
+	IsPublic        bool        `json:"is_public"`
+	Confidentiality bool        `json:"confidentiality"`
+}
+
+type cachedSection struct {
+	settings  map[string]Setting
+	expiresAt time.Time
+}
+
+type cachedAll struct {
+	settings  []Setting
+	expiresAt time.Time
+}
+
+// allGroupKey is the singleflight key All coalesces concurrent loads under;
+// it can't collide with a real section path since those come from the
+// section_path column and this one doesn't.
+const allGroupKey = "\x00all"
+
+// Store caches app.app_settings by section path, refreshed lazily from
+// PostgreSQL and invalidated across instances over Valkey pub/sub.
+type Store struct {
+	db     dbHandle
+	valkey *redis.Client
+	ttl    time.Duration
+
+	mu       sync.RWMutex
+	cache    map[string]cachedSection
+	allCache *cachedAll
+
+	group singleflight.Group
+}
+
+// NewStore creates a Store backed by db, publishing and subscribing to
+// invalidation events over valkey.
+func NewStore(db *pgxpool.Pool, valkey *redis.Client) *Store {
+	return &Store{
+		db:     db,
+		valkey: valkey,
+		ttl:    defaultTTL,
+		cache:  make(map[string]cachedSection),
+	}
+}
+
+// Section returns every setting under sectionPath, loading and caching it
+// from PostgreSQL on a miss or expiry.
+func (s *Store) Section(ctx context.Context, sectionPath string) (map[string]Setting, error) {
+	s.mu.RLock()
+	entry, ok := s.cache[sectionPath]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.settings, nil
+	}
+
+	v, err, _ := s.group.Do(sectionPath, func() (interface{}, error) {
+		return s.loadSection(ctx, sectionPath)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]Setting), nil
+}
+
+func (s *Store) loadSection(ctx context.Context, sectionPath string) (map[string]Setting, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT section_path, setting_name, value, is_public, confidentiality
+		FROM app.app_settings
+		WHERE section_path = $1`, sectionPath)
+	if err != nil {
+		return nil, fmt.Errorf("query app settings %q: %w", sectionPath, err)
+	}
+	defer rows.Close()
+
+	settingsByName := make(map[string]Setting)
+	for rows.Next() {
+		var setting Setting
+		if err := rows.Scan(&setting.SectionPath, &setting.SettingName, &setting.Value, &setting.IsPublic, &setting.Confidentiality); err != nil {
+			return nil, fmt.Errorf("scan app setting: %w", err)
+		}
+		settingsByName[setting.SettingName] = setting
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read app settings %q: %w", sectionPath, err)
+	}
+
+	s.mu.Lock()
+	s.cache[sectionPath] = cachedSection{settings: settingsByName, expiresAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return settingsByName, nil
+}
+
+// All returns every public, non-confidential setting across all sections,
+// loading and caching it from PostgreSQL on a miss or expiry. Unlike
+// Section it isn't scoped to one section_path, so it's used by the public
+// settings endpoint that serves the whole table at once.
+func (s *Store) All(ctx context.Context) ([]Setting, error) {
+	s.mu.RLock()
+	entry := s.allCache
+	s.mu.RUnlock()
+	if entry != nil && time.Now().Before(entry.expiresAt) {
+		return entry.settings, nil
+	}
+
+	v, err, _ := s.group.Do(allGroupKey, func() (interface{}, error) {
+		return s.loadAll(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]Setting), nil
+}
+
+func (s *Store) loadAll(ctx context.Context) ([]Setting, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT section_path, setting_name, value, is_public, confidentiality
+		FROM app.app_settings
+		WHERE is_public = true AND confidentiality = false`)
+	if err != nil {
+		return nil, fmt.Errorf("query public app settings: %w", err)
+	}
+	defer rows.Close()
+
+	var settingsList []Setting
+	for rows.Next() {
+		var setting Setting
+		if err := rows.Scan(&setting.SectionPath, &setting.SettingName, &setting.Value, &setting.IsPublic, &setting.Confidentiality); err != nil {
+			return nil, fmt.Errorf("scan app setting: %w", err)
+		}
+		settingsList = append(settingsList, setting)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read public app settings: %w", err)
+	}
+
+	s.mu.Lock()
+	s.allCache = &cachedAll{settings: settingsList, expiresAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return settingsList, nil
+}
+
+// GetString returns the string value of setting in sectionPath, reporting
+// false if it doesn't exist or isn't a string.
+func (s *Store) GetString(ctx context.Context, sectionPath, settingName string) (string, bool, error) {
+	setting, ok, err := s.get(ctx, sectionPath, settingName)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	str, ok := setting.Value.(string)
+	return str, ok, nil
+}
+
+// GetBool returns the bool value of setting in sectionPath, reporting
+// false if it doesn't exist or isn't a bool.
+func (s *Store) GetBool(ctx context.Context, sectionPath, settingName string) (bool, bool, error) {
+	setting, ok, err := s.get(ctx, sectionPath, settingName)
+	if err != nil || !ok {
+		return false, false, err
+	}
+	b, ok := setting.Value.(bool)
+	return b, ok, nil
+}
+
+// GetJSON unmarshals the value of setting in sectionPath into dest,
+// reporting false if the setting doesn't exist.
+func (s *Store) GetJSON(ctx context.Context, sectionPath, settingName string, dest interface{}) (bool, error) {
+	setting, ok, err := s.get(ctx, sectionPath, settingName)
+	if err != nil || !ok {
+		return ok, err
+	}
+	raw, err := json.Marshal(setting.Value)
+	if err != nil {
+		return false, fmt.Errorf("marshal setting %s/%s: %w", sectionPath, settingName, err)
+	}
+	if err := json.Unmarshal(raw, dest); err != nil {
+		return false, fmt.Errorf("unmarshal setting %s/%s: %w", sectionPath, settingName, err)
+	}
+	return true, nil
+}
+
+func (s *Store) get(ctx context.Context, sectionPath, settingName string) (Setting, bool, error) {
+	section, err := s.Section(ctx, sectionPath)
+	if err != nil {
+		return Setting{}, false, err
+	}
+	setting, ok := section[settingName]
+	return setting, ok, nil
+}
+
+// Invalidate drops sectionPath from this instance's cache and publishes an
+// invalidation event so every other instance drops it too.
+//
+// Nothing in this repo calls Invalidate yet: there is no settings-mutation
+// path (no admin API, no migration step) that changes app.app_settings
+// after the fact. It exists so whichever code ends up writing that table
+// has a ready-made way to push the change out immediately instead of
+// waiting on defaultTTL; until then, cache coherence across instances
+// relies entirely on the TTL expiring.
+func (s *Store) Invalidate(ctx context.Context, sectionPath string) error {
+	s.evict(sectionPath)
+	if err := s.valkey.Publish(ctx, invalidateChannel, sectionPath).Err(); err != nil {
+		return fmt.Errorf("publish settings invalidation for %q: %w", sectionPath, err)
+	}
+	return nil
+}
+
+// evict drops sectionPath from the per-section cache. allCache is cleared
+// unconditionally too, since it aggregates across every section and a
+// change to any one of them can change its result.
+func (s *Store) evict(sectionPath string) {
+	s.mu.Lock()
+	delete(s.cache, sectionPath)
+	s.allCache = nil
+	s.mu.Unlock()
+}
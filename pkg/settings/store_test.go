@@ -0,0 +1,145 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeRows is a minimal pgx.Rows backed by a fixed slice of column values,
+// enough to drive loadSection/loadAll's scan loop.
+type fakeRows struct {
+	data [][]any
+	idx  int
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	if r.idx >= len(r.data) {
+		return false
+	}
+	r.idx++
+	return true
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return r.data[r.idx-1], nil
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	row := r.data[r.idx-1]
+	for i, d := range dest {
+		switch dp := d.(type) {
+		case *string:
+			*dp = row[i].(string)
+		case *interface{}:
+			*dp = row[i]
+		case *bool:
+			*dp = row[i].(bool)
+		default:
+			return fmt.Errorf("fakeRows: unsupported dest type %T", d)
+		}
+	}
+	return nil
+}
+
+// fakeSettingsDB counts Query calls and, if gate is non-nil, blocks inside
+// Query until gate is closed - used to force concurrent callers to overlap
+// so singleflight coalescing can be observed.
+type fakeSettingsDB struct {
+	mu    sync.Mutex
+	calls int
+	gate  chan struct{}
+}
+
+func (f *fakeSettingsDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+
+	if f.gate != nil {
+		<-f.gate
+	}
+	return &fakeRows{data: [][]any{
+		{"ui", "theme", "dark", true, false},
+	}}, nil
+}
+
+func (f *fakeSettingsDB) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func newTestStore(db dbHandle, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl, cache: make(map[string]cachedSection)}
+}
+
+// TestSection_CachesWithinTTL verifies a section is served from cache
+// without hitting the DB again until its TTL expires.
+func TestSection_CachesWithinTTL(t *testing.T) {
+	db := &fakeSettingsDB{}
+	s := newTestStore(db, 30*time.Millisecond)
+	ctx := context.Background()
+
+	if _, err := s.Section(ctx, "ui"); err != nil {
+		t.Fatalf("Section: %v", err)
+	}
+	if _, err := s.Section(ctx, "ui"); err != nil {
+		t.Fatalf("Section: %v", err)
+	}
+	if got := db.callCount(); got != 1 {
+		t.Fatalf("Query calls within TTL: want 1, got %d", got)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, err := s.Section(ctx, "ui"); err != nil {
+		t.Fatalf("Section: %v", err)
+	}
+	if got := db.callCount(); got != 2 {
+		t.Fatalf("Query calls after TTL expiry: want 2, got %d", got)
+	}
+}
+
+// TestSection_ConcurrentCallsCoalesce verifies that multiple callers racing
+// on an empty cache for the same section path share a single DB load via
+// singleflight, rather than each issuing their own query.
+func TestSection_ConcurrentCallsCoalesce(t *testing.T) {
+	db := &fakeSettingsDB{gate: make(chan struct{})}
+	s := newTestStore(db, time.Minute)
+	ctx := context.Background()
+
+	const callers = 10
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := s.Section(ctx, "ui"); err != nil {
+				t.Errorf("Section: %v", err)
+			}
+		}()
+	}
+
+	// Give every goroutine a chance to block inside Query before releasing
+	// them all at once.
+	time.Sleep(20 * time.Millisecond)
+	close(db.gate)
+	wg.Wait()
+
+	if got := db.callCount(); got != 1 {
+		t.Fatalf("concurrent Section calls: want 1 coalesced Query, got %d", got)
+	}
+}
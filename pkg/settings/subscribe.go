@@ -0,0 +1,37 @@
+// Version: 1.0.0
+// Purpose: Cross-instance cache invalidation for Store over Valkey pub/sub.
+// Backend file: subscribe.go
+
+package settings
+
+import (
+	"context"
+	"log/slog"
+)
+
+// invalidateChannel is the Valkey pub/sub channel Invalidate publishes on
+// and Subscribe listens to. The message payload is the section path to
+// evict.
+const invalidateChannel = "settings:invalidate"
+
+// Subscribe listens for invalidation events on invalidateChannel until ctx
+// is cancelled, evicting the named section from this instance's cache as
+// events arrive. It blocks, so callers should run it in a goroutine.
+func (s *Store) Subscribe(ctx context.Context) error {
+	pubsub := s.valkey.Subscribe(ctx, invalidateChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			s.evict(msg.Payload)
+			slog.Debug("settings cache invalidated", "section_path", msg.Payload)
+		}
+	}
+}
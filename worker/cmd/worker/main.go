@@ -1,91 +1,120 @@
-// Version: 1.0.0
-// Purpose: Entry point for evi-worker. Bootstraps config, zerolog, PostgreSQL, Valkey,
-//          and the polling loop. Handles graceful shutdown on SIGINT/SIGTERM.
+// Version: 3.0.0
+// Purpose: Entry point for evi-worker. Bootstraps config, the shared slog
+//          logger, PostgreSQL, Valkey, and the polling loop. Each subsystem
+//          runs as an errgroup member so that SIGINT/SIGTERM cancels a
+//          shared root context, stops new job delivery immediately, and
+//          waits up to SHUTDOWN_TIMEOUT for in-flight jobs to drain before
+//          forcing pools closed.
 // Backend file: main.go
 
 package main
 
 import (
 	"context"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/errgroup"
 
+	"github.com/vk74/evi/pkg/authkeys"
+	"github.com/vk74/evi/pkg/log"
+	sharedmetrics "github.com/vk74/evi/pkg/metrics"
+	"github.com/vk74/evi/pkg/settings"
 	"github.com/vk74/evi/worker/internal/config"
 	"github.com/vk74/evi/worker/internal/infra"
+	"github.com/vk74/evi/worker/internal/jobs"
+	"github.com/vk74/evi/worker/internal/metrics"
 	"github.com/vk74/evi/worker/internal/worker"
 )
 
+// version and commit are set via -ldflags at build time.
+var (
+	version = "dev"
+	commit  = "none"
+)
+
 func main() {
 	// Load .env before reading any env vars; ignore error if file is absent
 	_ = godotenv.Load()
 
-	// Configure zerolog output format and level from env vars
-	setupLogger()
-
 	cfg := config.Load()
-	log.Info().
-		Str("logLevel", cfg.LogLevel).
-		Str("logFormat", cfg.LogFormat).
-		Msg("evi-worker starting")
 
-	// Use a timeout context for all startup I/O operations
-	initCtx, cancelInit := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancelInit()
+	// Install the shared slog logger so worker, infra, and poller code all
+	// log through the same handler.
+	log.New(log.Config{Level: cfg.LogLevel, Format: cfg.LogFormat})
+
+	slog.Info("evi-worker starting", "logLevel", cfg.LogLevel, "logFormat", cfg.LogFormat)
+
+	sharedmetrics.BuildInfo(prometheus.DefaultRegisterer, version, commit)
+
+	// Root context for the process: cancelled on SIGINT/SIGTERM, at which
+	// point every subsystem below starts draining.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Use a bounded context for startup I/O specifically, still tied to the
+	// root so a signal during startup aborts connecting too.
+	initCtx, cancelInit := context.WithTimeout(ctx, 15*time.Second)
 
 	db, err := infra.NewDB(initCtx, cfg.DatabaseURL)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to PostgreSQL")
+		cancelInit()
+		slog.Error("Failed to connect to PostgreSQL", "error", err)
+		os.Exit(1)
 	}
 	defer db.Close()
+	metrics.RegisterDBPool(db)
 
 	valkeyClient, err := infra.NewValkey(initCtx, cfg.ValkeyURL, cfg.ValkeyPassword)
+	cancelInit()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to Valkey")
+		slog.Error("Failed to connect to Valkey", "error", err)
+		os.Exit(1)
 	}
 	defer valkeyClient.Close()
+	sharedmetrics.InstrumentValkey(valkeyClient)
 
-	// Worker lifecycle context — cancelled on shutdown signal
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	settingsStore := settings.NewStore(db, valkeyClient)
 
-	poller := worker.NewPoller(db, valkeyClient)
-	go poller.Run(ctx)
+	registry := worker.NewRegistry()
+	// Job handlers are registered here as they are added, e.g.:
+	//   registry.Register("notifications", "email.send", emailHandler)
+	registry.Register(jobs.StreamMaintenance, jobs.JobRotateSigningKeys, jobs.NewRotateSigningKeysHandler(authkeys.NewStore(db)))
 
-	// Block until SIGINT (Ctrl+C) or SIGTERM (container stop)
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-quit
+	poller := worker.NewPoller(db, valkeyClient, registry, cfg.WorkerConcurrency, cfg.ShutdownTimeout)
+	jobStore := worker.NewStore(db)
 
-	log.Info().Str("signal", sig.String()).Msg("Shutdown signal received, stopping...")
-	cancel()
+	// Each subsystem below is its own errgroup member: it returns when ctx
+	// is cancelled and its error (if any) is what determines main's exit
+	// status.
+	g, gctx := errgroup.WithContext(ctx)
 
-	// Brief drain window for the poller goroutine to exit cleanly
-	time.Sleep(500 * time.Millisecond)
-	log.Info().Msg("evi-worker stopped")
-}
+	g.Go(func() error {
+		return poller.Run(gctx)
+	})
+
+	g.Go(func() error {
+		return jobs.RunRotationTicker(gctx, valkeyClient, jobStore, cfg.KeyRotationInterval)
+	})
 
-// setupLogger configures the global zerolog logger.
-// LOG_FORMAT=pretty (default) → colored human-readable output for dev terminals.
-// LOG_FORMAT=json → structured JSON lines for production / container log collectors.
-func setupLogger() {
-	lvl, err := zerolog.ParseLevel(os.Getenv("LOG_LEVEL"))
-	if err != nil || os.Getenv("LOG_LEVEL") == "" {
-		lvl = zerolog.DebugLevel
+	g.Go(func() error {
+		return settingsStore.Subscribe(gctx)
+	})
+
+	if cfg.MetricsBindAddr != "" {
+		g.Go(func() error {
+			return metrics.Serve(gctx, cfg.MetricsBindAddr)
+		})
 	}
-	zerolog.SetGlobalLevel(lvl)
-
-	if os.Getenv("LOG_FORMAT") == "json" {
-		log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
-	} else {
-		log.Logger = zerolog.New(zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: "15:04:05",
-		}).With().Timestamp().Logger()
+
+	if err := g.Wait(); err != nil {
+		slog.Error("evi-worker stopped with error", "error", err)
+		os.Exit(1)
 	}
+	slog.Info("evi-worker stopped")
 }
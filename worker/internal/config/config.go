@@ -5,29 +5,59 @@
 
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 // Config holds all runtime configuration values for evi-worker.
 type Config struct {
-	DatabaseURL    string
-	ValkeyURL      string
-	ValkeyPassword string
-	LogLevel       string
-	LogFormat      string
+	DatabaseURL       string
+	ValkeyURL         string
+	ValkeyPassword    string
+	LogLevel          string
+	LogFormat         string
+	WorkerConcurrency int
+	// MetricsBindAddr is where the admin metrics HTTP server listens.
+	// Blank disables it. Defaults to loopback-only so it isn't exposed
+	// publicly alongside the (non-existent) worker API surface.
+	MetricsBindAddr string
+	// ShutdownTimeout bounds how long graceful shutdown waits for
+	// in-flight jobs to finish and be acked before forcing pools closed.
+	ShutdownTimeout time.Duration
+	// KeyRotationInterval is how often a JobRotateSigningKeys job is
+	// enqueued onto the maintenance stream.
+	KeyRotationInterval time.Duration
 }
 
 // Load builds Config from environment variables.
 // Must be called after godotenv.Load() in main.go so that .env values are visible.
 func Load() *Config {
 	return &Config{
-		DatabaseURL:    getEnv("DATABASE_URL", "postgresql://app_service:P@ssw0rd@localhost:5445/maindb"),
-		ValkeyURL:      getEnv("VALKEY_URL", "localhost:6379"),
-		ValkeyPassword: getEnv("VALKEY_PASSWORD", ""),
-		LogLevel:       getEnv("LOG_LEVEL", "debug"),
-		LogFormat:      getEnv("LOG_FORMAT", "pretty"),
+		DatabaseURL:         getEnv("DATABASE_URL", "postgresql://app_service:P@ssw0rd@localhost:5445/maindb"),
+		ValkeyURL:           getEnv("VALKEY_URL", "localhost:6379"),
+		ValkeyPassword:      getEnv("VALKEY_PASSWORD", ""),
+		LogLevel:            getEnv("LOG_LEVEL", "debug"),
+		LogFormat:           getEnv("LOG_FORMAT", "pretty"),
+		WorkerConcurrency:   getEnvInt("WORKER_CONCURRENCY", 4),
+		MetricsBindAddr:     getEnv("METRICS_BIND_ADDR", "127.0.0.1:9090"),
+		ShutdownTimeout:     time.Duration(getEnvInt("SHUTDOWN_TIMEOUT", 30)) * time.Second,
+		KeyRotationInterval: time.Duration(getEnvInt("KEY_ROTATION_INTERVAL", 3600)) * time.Second,
 	}
 }
 
+// getEnvInt returns the integer value of key from the environment, or
+// defaultVal if not set or not parseable.
+func getEnvInt(key string, defaultVal int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultVal
+}
+
 // getEnv returns the value of key from the environment, or defaultVal if not set.
 func getEnv(key, defaultVal string) string {
 	if value, exists := os.LookupEnv(key); exists {
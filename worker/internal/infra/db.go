@@ -8,16 +8,16 @@ package infra
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/rs/zerolog/log"
 )
 
 // NewDB creates, configures, and validates a pgx/v5 connection pool.
 // Returns the pool directly so callers can use pgxpool.Pool methods.
 func NewDB(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
-	log.Info().Msg("Connecting to PostgreSQL...")
+	slog.Info("Connecting to PostgreSQL...")
 
 	cfg, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
@@ -40,6 +40,6 @@ func NewDB(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("ping database: %w", err)
 	}
 
-	log.Info().Msg("PostgreSQL connected")
+	slog.Info("PostgreSQL connected")
 	return pool, nil
 }
@@ -8,15 +8,15 @@ package infra
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/redis/go-redis/v9"
-	"github.com/rs/zerolog/log"
 )
 
 // NewValkey creates a go-redis/v9 client and verifies connectivity via PING.
 // addr format: "host:port" (e.g. "localhost:6379").
 func NewValkey(ctx context.Context, addr, password string) (*redis.Client, error) {
-	log.Info().Str("addr", addr).Msg("Connecting to Valkey...")
+	slog.Info("Connecting to Valkey...", "addr", addr)
 
 	client := redis.NewClient(&redis.Options{
 		Addr:     addr,
@@ -29,6 +29,6 @@ func NewValkey(ctx context.Context, addr, password string) (*redis.Client, error
 		return nil, fmt.Errorf("ping valkey: %w", err)
 	}
 
-	log.Info().Msg("Valkey connected")
+	slog.Info("Valkey connected")
 	return client, nil
 }
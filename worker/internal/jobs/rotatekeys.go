@@ -0,0 +1,68 @@
+// Version: 1.0.0
+// Purpose: Job handler that rotates the API's RS256 signing key: generates
+//          a fresh keypair and retires keys older than the access-token
+//          TTL window, so tokens signed just before a rotation still
+//          verify until they expire.
+// Backend file: rotatekeys.go
+
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/vk74/evi/pkg/authkeys"
+	"github.com/vk74/evi/worker/internal/worker"
+)
+
+// StreamMaintenance is the stream maintenance jobs (like key rotation) are
+// published on.
+const StreamMaintenance = "maintenance"
+
+// JobRotateSigningKeys is the job type RotateSigningKeysHandler registers for.
+const JobRotateSigningKeys = "auth.rotate_keys"
+
+// RotateSigningKeysHandler generates a new signing key and retires any key
+// older than authkeys.AccessTokenTTL.
+type RotateSigningKeysHandler struct {
+	Keys *authkeys.Store
+}
+
+func NewRotateSigningKeysHandler(keys *authkeys.Store) *RotateSigningKeysHandler {
+	return &RotateSigningKeysHandler{Keys: keys}
+}
+
+func (h *RotateSigningKeysHandler) Handle(ctx context.Context, job *worker.Job) error {
+	if _, err := h.Keys.Generate(ctx); err != nil {
+		return fmt.Errorf("rotate signing keys: generate: %w", err)
+	}
+	if err := h.Keys.RetireExpired(ctx, authkeys.AccessTokenTTL); err != nil {
+		return fmt.Errorf("rotate signing keys: retire expired: %w", err)
+	}
+	return nil
+}
+
+// RunRotationTicker enqueues a JobRotateSigningKeys job onto
+// StreamMaintenance every interval until ctx is cancelled, so
+// RotateSigningKeysHandler actually runs on a schedule instead of relying on
+// something else to enqueue it. Errors are logged rather than returned,
+// since a single failed enqueue shouldn't stop future ticks.
+func RunRotationTicker(ctx context.Context, valkey *redis.Client, store *worker.Store, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := worker.Enqueue(ctx, valkey, store, StreamMaintenance, JobRotateSigningKeys, nil); err != nil {
+				slog.Error("rotation ticker: failed to enqueue key rotation job", "error", err)
+			}
+		}
+	}
+}
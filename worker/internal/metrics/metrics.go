@@ -0,0 +1,97 @@
+// Version: 1.0.0
+// Purpose: Prometheus metrics for evi-worker: job counters/histograms and
+//          the admin HTTP server that serves them. The admin server binds
+//          to whatever address config.MetricsBindAddr says, so operators
+//          can keep it off the public network (e.g. 127.0.0.1:9090).
+// Backend file: metrics.go
+
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+
+	sharedmetrics "github.com/vk74/evi/pkg/metrics"
+)
+
+var (
+	JobsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "jobs_processed_total",
+		Help: "Job attempts processed, labeled by stream and outcome (succeeded/failed); a failed attempt that is retried counts as failed.",
+	}, []string{"stream", "status"})
+
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "job_duration_seconds",
+		Help: "Time spent in Handler.Handle, labeled by stream.",
+	}, []string{"stream"})
+
+	JobsInflight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "jobs_inflight",
+		Help: "Jobs currently being processed, labeled by stream.",
+	}, []string{"stream"})
+
+	StreamLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "stream_lag",
+		Help: "Entries in the stream not yet delivered to the consumer group, labeled by stream.",
+	}, []string{"stream"})
+)
+
+func init() {
+	prometheus.MustRegister(JobsProcessed, JobDuration, JobsInflight, StreamLag)
+}
+
+// RegisterDBPool registers a collector that exports pool.Stat() as
+// db_pool_* gauges and counters on every /metrics scrape.
+func RegisterDBPool(pool *pgxpool.Pool) {
+	prometheus.MustRegister(sharedmetrics.NewDBPoolCollector(pool))
+}
+
+// Serve starts the admin metrics HTTP server on addr and blocks until ctx
+// is cancelled, then shuts it down. A blank addr means the caller should
+// not call Serve at all; main.go is responsible for that check.
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("worker metrics server listening", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		return err
+	}
+}
+
+// RefreshStreamLag recomputes stream_lag for stream using the consumer
+// group's reported lag (entries not yet delivered to any consumer).
+func RefreshStreamLag(ctx context.Context, valkey *redis.Client, consumerGroup, stream string) {
+	groups, err := valkey.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		slog.Error("stream lag: XINFO GROUPS failed", "stream", stream, "error", err)
+		return
+	}
+
+	for _, g := range groups {
+		if g.Name == consumerGroup {
+			StreamLag.WithLabelValues(stream).Set(float64(g.Lag))
+			return
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Version: 1.0.0
+// Purpose: Delayed-retry queue backed by a Valkey sorted set. Failed jobs
+//          are scheduled here scored by their redelivery time; a background
+//          reaper moves due entries back onto their origin stream.
+// Backend file: delayed.go
+
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const delayedSetKey = "jobs:delayed"
+
+// delayedEntry is the JSON payload stored as a sorted-set member, scored by
+// the unix timestamp at which the job becomes eligible for redelivery.
+type delayedEntry struct {
+	Stream    string                 `json:"stream"`
+	Type      string                 `json:"type"`
+	Attempt   int                    `json:"attempt"`
+	JobID     string                 `json:"job_id"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Payload   map[string]interface{} `json:"payload"`
+}
+
+// scheduleRetry stores job in the delayed set for redelivery to its origin
+// stream after delay.
+func scheduleRetry(ctx context.Context, valkey *redis.Client, job *Job, delay time.Duration) error {
+	entry := delayedEntry{
+		Stream:    job.Stream,
+		Type:      job.Type,
+		Attempt:   job.Attempt,
+		JobID:     job.JobID,
+		RequestID: job.RequestID,
+		Payload:   job.Payload,
+	}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	score := float64(time.Now().Add(delay).Unix())
+	return valkey.ZAdd(ctx, delayedSetKey, redis.Z{Score: score, Member: raw}).Err()
+}
+
+// delayedReaper periodically moves due entries from the delayed sorted set
+// back onto their origin stream as new XADD entries.
+type delayedReaper struct {
+	valkey   *redis.Client
+	interval time.Duration
+}
+
+func newDelayedReaper(valkey *redis.Client, interval time.Duration) *delayedReaper {
+	return &delayedReaper{valkey: valkey, interval: interval}
+}
+
+func (r *delayedReaper) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reapDue(ctx)
+		}
+	}
+}
+
+// reapDue moves every entry scored at or before now back onto its stream.
+func (r *delayedReaper) reapDue(ctx context.Context) {
+	max := strconv.FormatInt(time.Now().Unix(), 10)
+	members, err := r.valkey.ZRangeByScore(ctx, delayedSetKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: max,
+	}).Result()
+	if err != nil {
+		slog.Error("delayed reaper: ZRANGEBYSCORE failed", "error", err)
+		return
+	}
+
+	for _, raw := range members {
+		var entry delayedEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			slog.Error("delayed reaper: dropping malformed entry", "error", err)
+			_ = r.valkey.ZRem(ctx, delayedSetKey, raw).Err()
+			continue
+		}
+
+		// Claim the entry before requeuing it: if ZREM fails, the entry is
+		// simply left for the next tick, but if it ran after XADD and
+		// failed there, a flaky ZREM would otherwise leave a past-due
+		// score in the set that gets re-XADDed on every subsequent tick,
+		// duplicating delivery until ZREM happened to succeed.
+		removed, err := r.valkey.ZRem(ctx, delayedSetKey, raw).Result()
+		if err != nil {
+			slog.Error("delayed reaper: ZREM failed, leaving entry for next tick", "error", err)
+			continue
+		}
+		if removed == 0 {
+			// Another reaper removed the entry first and requeues it itself.
+			continue
+		}
+
+		fields := map[string]interface{}{"type": entry.Type, "attempt": entry.Attempt, "job_id": entry.JobID}
+		if entry.RequestID != "" {
+			fields["request_id"] = entry.RequestID
+		}
+		for k, v := range entry.Payload {
+			fields[k] = v
+		}
+
+		if err := r.valkey.XAdd(ctx, &redis.XAddArgs{Stream: entry.Stream, Values: fields}).Err(); err != nil {
+			slog.Error("delayed reaper: XADD failed after claiming entry, rescheduling", "stream", entry.Stream, "error", err)
+			rescore := float64(time.Now().Unix())
+			if err := r.valkey.ZAdd(ctx, delayedSetKey, redis.Z{Score: rescore, Member: raw}).Err(); err != nil {
+				slog.Error("delayed reaper: failed to reschedule after XADD failure, entry lost", "error", err)
+			}
+		}
+	}
+}
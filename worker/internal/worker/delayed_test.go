@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestValkey(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return redis.NewClient(&redis.Options{Addr: mr.Addr()})
+}
+
+// TestReapDue_RequeuesDueEntry verifies the happy path: a delayed entry
+// scored in the past is moved off jobs:delayed and onto its origin stream.
+func TestReapDue_RequeuesDueEntry(t *testing.T) {
+	ctx := context.Background()
+	valkey := newTestValkey(t)
+
+	job := &Job{Stream: "orders", Type: "ship", JobID: "job-1", Attempt: 2}
+	if err := scheduleRetry(ctx, valkey, job, -time.Second); err != nil {
+		t.Fatalf("scheduleRetry: %v", err)
+	}
+
+	r := newDelayedReaper(valkey, time.Second)
+	r.reapDue(ctx)
+
+	count, err := valkey.ZCard(ctx, delayedSetKey).Result()
+	if err != nil {
+		t.Fatalf("ZCARD: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("delayed set: want 0 entries after reap, got %d", count)
+	}
+
+	msgs, err := valkey.XRange(ctx, "orders", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRANGE: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("origin stream: want 1 requeued entry, got %d", len(msgs))
+	}
+	if msgs[0].Values["job_id"] != "job-1" {
+		t.Fatalf("requeued entry: want job_id job-1, got %v", msgs[0].Values["job_id"])
+	}
+}
+
+// TestReapDue_NotYetDueEntryIsUntouched verifies an entry scored in the
+// future is left alone.
+func TestReapDue_NotYetDueEntryIsUntouched(t *testing.T) {
+	ctx := context.Background()
+	valkey := newTestValkey(t)
+
+	job := &Job{Stream: "orders", Type: "ship", JobID: "job-2", Attempt: 1}
+	if err := scheduleRetry(ctx, valkey, job, time.Hour); err != nil {
+		t.Fatalf("scheduleRetry: %v", err)
+	}
+
+	r := newDelayedReaper(valkey, time.Second)
+	r.reapDue(ctx)
+
+	count, err := valkey.ZCard(ctx, delayedSetKey).Result()
+	if err != nil {
+		t.Fatalf("ZCARD: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("delayed set: want the not-yet-due entry left in place, got %d entries", count)
+	}
+}
+
+// TestReapDue_SecondTickDoesNotRequeueAgain verifies that once a due entry
+// has been reaped it is no longer claimable from jobs:delayed, so a second
+// tick does not XADD it onto the stream a second time. miniredis cannot
+// make ZREM fail, so the ZREM-before-XADD ordering itself is not observed
+// here.
+func TestReapDue_SecondTickDoesNotRequeueAgain(t *testing.T) {
+	ctx := context.Background()
+	valkey := newTestValkey(t)
+
+	job := &Job{Stream: "orders", Type: "ship", JobID: "job-3", Attempt: 1}
+	if err := scheduleRetry(ctx, valkey, job, -time.Second); err != nil {
+		t.Fatalf("scheduleRetry: %v", err)
+	}
+
+	r := newDelayedReaper(valkey, time.Second)
+	r.reapDue(ctx)
+	r.reapDue(ctx) // a second tick must not see the entry again
+
+	msgs, err := valkey.XRange(ctx, "orders", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRANGE: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("origin stream: want exactly 1 delivery across two ticks, got %d (duplicate delivery)", len(msgs))
+	}
+}
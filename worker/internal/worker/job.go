@@ -0,0 +1,66 @@
+// Version: 1.0.0
+// Purpose: Job and Handler types shared by the poller, worker pool, and
+//          retry/DLQ logic.
+// Backend file: job.go
+
+package worker
+
+import (
+	"context"
+	"strconv"
+)
+
+// Job represents a single unit of work read from a Valkey stream.
+type Job struct {
+	ID        string                 // Valkey stream entry ID of the current delivery; changes on every retry
+	JobID     string                 // stable logical job ID, generated at Enqueue and carried across retries
+	Stream    string                 // source stream name
+	Type      string                 // job type, used to route to a Handler
+	Attempt   int                    // delivery attempt count, starts at 1
+	RequestID string                 // correlation ID of the request that enqueued the job, if any
+	Payload   map[string]interface{} // job-specific fields
+}
+
+// Handler processes a single Job. A non-nil error marks the job as failed
+// so the poller can retry it with backoff or move it to the DLQ.
+type Handler interface {
+	Handle(ctx context.Context, job *Job) error
+}
+
+// HandlerFunc adapts a plain function to the Handler interface.
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+func (f HandlerFunc) Handle(ctx context.Context, job *Job) error {
+	return f(ctx, job)
+}
+
+// fieldsToJob converts the raw fields of a Valkey stream entry into a Job.
+// "type", "attempt", "request_id", and "job_id" are reserved fields;
+// everything else is carried through as the payload.
+func fieldsToJob(stream, id string, fields map[string]interface{}) *Job {
+	job := &Job{
+		ID:      id,
+		Stream:  stream,
+		Attempt: 1,
+		Payload: make(map[string]interface{}, len(fields)),
+	}
+	for k, v := range fields {
+		switch k {
+		case "type":
+			job.Type, _ = v.(string)
+		case "request_id":
+			job.RequestID, _ = v.(string)
+		case "job_id":
+			job.JobID, _ = v.(string)
+		case "attempt":
+			if s, ok := v.(string); ok {
+				if n, err := strconv.Atoi(s); err == nil {
+					job.Attempt = n
+				}
+			}
+		default:
+			job.Payload[k] = v
+		}
+	}
+	return job
+}
@@ -1,46 +1,414 @@
-// Version: 1.0.0
-// Purpose: Mock polling loop for evi-worker.
-//          Ticks every second and logs "Waiting for jobs..." until context is cancelled.
-//          Placeholder for the real Valkey queue consumer (XREADGROUP / BLPOP).
+// Version: 2.1.0
+// Purpose: Valkey Streams consumer for evi-worker. Bootstraps a consumer
+//          group per registered stream, fans delivered messages out to a
+//          worker pool, and applies ack/retry/DLQ semantics for delivery.
+//          Run honors graceful-shutdown deadlines for in-flight jobs.
 // Backend file: poller.go
 
 package worker
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
-	"github.com/rs/zerolog/log"
+
+	evilog "github.com/vk74/evi/pkg/log"
+	"github.com/vk74/evi/worker/internal/metrics"
+)
+
+const (
+	consumerGroup = "evi-workers"
+	deadSuffix    = ":dead"
+	maxAttempts   = 5
+	blockDuration = 5 * time.Second
+	// claimMinIdle must comfortably clear how long a handler can run, since
+	// a pending entry this idle is assumed abandoned and gets redelivered.
+	// reapStream also skips entries still owned by this same consumer name
+	// (see the uniqueness note on the consumer field in NewPoller), but a
+	// different instance's slow handler isn't caught by that check.
+	claimMinIdle    = 5 * time.Minute
+	claimInterval   = 15 * time.Second
+	delayedInterval = 5 * time.Second
+	lagInterval     = 15 * time.Second
+	baseBackoff     = 2 * time.Second
 )
 
 // Poller holds infrastructure dependencies and drives the job processing loop.
 type Poller struct {
-	db     *pgxpool.Pool
-	valkey *redis.Client
+	db              *pgxpool.Pool
+	valkey          *redis.Client
+	registry        *Registry
+	store           *Store
+	consumer        string
+	concurrency     int
+	shutdownTimeout time.Duration
 }
 
 // NewPoller creates a Poller with injected DB and Valkey dependencies.
-func NewPoller(db *pgxpool.Pool, valkey *redis.Client) *Poller {
-	return &Poller{db: db, valkey: valkey}
+// Handlers must be registered on registry before Run is called.
+// shutdownTimeout bounds how long Run waits for in-flight jobs to finish
+// and be XACKed once ctx is cancelled, before returning anyway.
+func NewPoller(db *pgxpool.Pool, valkey *redis.Client, registry *Registry, concurrency int, shutdownTimeout time.Duration) *Poller {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "evi-worker"
+	}
+	// The consumer name must be unique per process instance, not just per
+	// host: reapStream refuses to reclaim pending entries still owned by
+	// this same consumer name, on the assumption that they belong to a
+	// handler still running in this process. A bare hostname is stable
+	// across an in-place restart (a respawned container, a k8s pod), so
+	// without the pid+random suffix, every message pending when the old
+	// instance died would look "still being worked" to its successor
+	// forever - never retried, never dead-lettered.
+	consumer := fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	return &Poller{
+		db:              db,
+		valkey:          valkey,
+		registry:        registry,
+		store:           NewStore(db),
+		consumer:        consumer,
+		concurrency:     concurrency,
+		shutdownTimeout: shutdownTimeout,
+	}
 }
 
-// Run starts the polling loop. Blocks until ctx is cancelled.
-// Each tick logs a heartbeat message; real queue reads will replace this.
-func (p *Poller) Run(ctx context.Context) {
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
+// Run bootstraps consumer groups for every registered stream and starts the
+// read loop, worker pool, delayed-retry reaper, and idle-claim reaper. It
+// blocks until ctx is cancelled, at which point the read loop stops pulling
+// new deliveries immediately and Run waits up to shutdownTimeout for
+// in-flight jobs to finish and be acked before returning.
+func (p *Poller) Run(ctx context.Context) error {
+	streams := p.registry.Streams()
+	if len(streams) == 0 {
+		slog.Warn("poller: no streams registered, nothing to consume")
+		<-ctx.Done()
+		return nil
+	}
+
+	for _, stream := range streams {
+		if err := p.bootstrapGroup(ctx, stream); err != nil {
+			slog.Error("poller: failed to bootstrap consumer group", "stream", stream, "error", err)
+		}
+	}
+
+	jobs := make(chan readMsg, p.concurrency)
+
+	// drainCtx is detached from ctx's cancellation so that in-flight
+	// handler.Handle/XAck/XDel calls aren't aborted the instant SIGTERM
+	// arrives - that would leave their messages unacked in the PEL instead
+	// of drained. It's bounded by shutdownTimeout below via cancelDrain,
+	// same as the wg.Wait() bound it always had.
+	drainCtx, cancelDrain := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelDrain()
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.worker(drainCtx, jobs)
+		}()
+	}
+
+	deliver := func(ctx context.Context, stream string, msg redis.XMessage, reclaimAttempt int) {
+		select {
+		case jobs <- readMsg{stream: stream, msg: msg, reclaimAttempt: reclaimAttempt}:
+		case <-ctx.Done():
+		}
+	}
+
+	var bgWG sync.WaitGroup
+
+	reaper := newClaimReaper(p.valkey, consumerGroup, p.consumer, streams, claimMinIdle, claimInterval, deliver)
+	bgWG.Add(1)
+	go func() {
+		defer bgWG.Done()
+		reaper.run(ctx)
+	}()
 
-	log.Info().Msg("Poller started, waiting for jobs...")
+	delayed := newDelayedReaper(p.valkey, delayedInterval)
+	bgWG.Add(1)
+	go func() {
+		defer bgWG.Done()
+		delayed.run(ctx)
+	}()
+
+	bgWG.Add(1)
+	go func() {
+		defer bgWG.Done()
+		p.reportStreamLag(ctx, streams)
+	}()
+
+	slog.Info("Poller started, waiting for jobs...", "streams", streams, "concurrency", p.concurrency)
+
+	p.readLoop(ctx, streams, deliver)
+
+	// reaper is the only background goroutine that can still call deliver
+	// (onto jobs) after readLoop returns, so it must be fully stopped before
+	// jobs is closed, or a reclaim landing mid-shutdown panics on a send to
+	// a closed channel. delayed and reportStreamLag don't send on jobs, but
+	// are waited on here too so every goroutine started above is accounted
+	// for by the time Run returns.
+	bgWG.Wait()
+	close(jobs)
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		slog.Info("Poller stopped, all in-flight jobs drained")
+	case <-time.After(p.shutdownTimeout):
+		slog.Warn("poller: shutdown timeout exceeded, cancelling in-flight jobs", "timeout", p.shutdownTimeout)
+		cancelDrain()
+	}
+	return nil
+}
+
+// bootstrapGroup idempotently creates the shared consumer group on stream,
+// creating the stream itself if it does not yet exist.
+func (p *Poller) bootstrapGroup(ctx context.Context, stream string) error {
+	err := p.valkey.XGroupCreateMkStream(ctx, stream, consumerGroup, "$").Err()
+	if err != nil && !isBusyGroupErr(err) {
+		return err
+	}
+	return nil
+}
+
+func isBusyGroupErr(err error) bool {
+	return err != nil && err.Error() == "BUSYGROUP Consumer Group name already exists"
+}
+
+type readMsg struct {
+	stream string
+	msg    redis.XMessage
+	// reclaimAttempt is the delivery count Valkey reports for this pending
+	// entry (via XPENDING) at the moment the idle-claim reaper reclaimed
+	// it, or 0 for a fresh delivery off the stream. It's Redis's own count,
+	// so it survives the original consumer's crash even if that consumer
+	// never got to persist an attempt anywhere itself.
+	reclaimAttempt int
+}
+
+// readLoop blocks on XREADGROUP across all registered streams until ctx is
+// cancelled, handing each delivered message to the worker pool.
+func (p *Poller) readLoop(ctx context.Context, streams []string, deliver func(context.Context, string, redis.XMessage, int)) {
+	args := make([]string, 0, len(streams)*2)
+	args = append(args, streams...)
+	for range streams {
+		args = append(args, ">")
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		res, err := p.valkey.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    consumerGroup,
+			Consumer: p.consumer,
+			Streams:  args,
+			Count:    int64(p.concurrency),
+			Block:    blockDuration,
+		}).Result()
+		if err != nil {
+			if err == redis.Nil || ctx.Err() != nil {
+				continue
+			}
+			slog.Error("poller: XREADGROUP failed", "error", err)
+			continue
+		}
+
+		for _, stream := range res {
+			for _, msg := range stream.Messages {
+				deliver(ctx, stream.Stream, msg, 0)
+			}
+		}
+	}
+}
+
+// reportStreamLag periodically refreshes the stream_lag gauge for every
+// registered stream until ctx is cancelled.
+func (p *Poller) reportStreamLag(ctx context.Context, streams []string) {
+	ticker := time.NewTicker(lagInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info().Msg("Poller stopped")
 			return
 		case <-ticker.C:
-			log.Debug().Msg("Waiting for jobs...")
+			for _, stream := range streams {
+				metrics.RefreshStreamLag(ctx, p.valkey, consumerGroup, stream)
+			}
 		}
 	}
 }
+
+// worker pulls delivered messages from jobs and processes them until the
+// channel is closed.
+func (p *Poller) worker(ctx context.Context, jobs <-chan readMsg) {
+	for rm := range jobs {
+		p.process(ctx, rm.stream, rm.msg, rm.reclaimAttempt)
+	}
+}
+
+// process dispatches a single delivered message to its registered Handler
+// and applies ack/retry/DLQ semantics based on the outcome. job_id and
+// request_id are attached to ctx so handler code and DB queries it makes
+// can correlate their own logs back to this delivery. reclaimAttempt, when
+// larger than the attempt count carried on the stream entry itself,
+// replaces it: the idle-claim reaper hands a message back here after its
+// original consumer went dark without ever ack'ing, retrying, or
+// dead-lettering it, so the stream entry's own "attempt" field is stale.
+// Raising job.Attempt to Valkey's delivery count (rather than leaving it at
+// whatever the entry was last written with) is what lets a handler that
+// reliably crashes the whole process still count toward maxAttempts and
+// eventually reach the DLQ.
+func (p *Poller) process(ctx context.Context, stream string, msg redis.XMessage, reclaimAttempt int) {
+	job := fieldsToJob(stream, msg.ID, msg.Values)
+	if reclaimAttempt > job.Attempt {
+		job.Attempt = reclaimAttempt
+	}
+	ctx = evilog.WithJobID(ctx, job.JobID)
+	if job.RequestID != "" {
+		ctx = evilog.WithRequestID(ctx, job.RequestID)
+	}
+	logger := slog.With("job_id", job.JobID, "request_id", job.RequestID, "stream", stream, "type", job.Type)
+
+	p.store.Upsert(ctx, job, JobRunning, "")
+
+	handler, err := p.registry.lookup(stream, job.Type)
+	if err != nil {
+		logger.Error("process: no handler, sending to DLQ", "error", err)
+		p.deadLetter(ctx, stream, job, err.Error())
+		p.ack(ctx, stream, job)
+		return
+	}
+
+	metrics.JobsInflight.WithLabelValues(stream).Inc()
+	start := time.Now()
+	err = p.invokeHandler(ctx, handler, job)
+	metrics.JobDuration.WithLabelValues(stream).Observe(time.Since(start).Seconds())
+	metrics.JobsInflight.WithLabelValues(stream).Dec()
+
+	if err != nil {
+		metrics.JobsProcessed.WithLabelValues(stream, string(JobFailed)).Inc()
+		p.retryOrDeadLetter(ctx, stream, job, err)
+		return
+	}
+
+	p.ack(ctx, stream, job)
+	p.store.Upsert(ctx, job, JobSucceeded, "")
+	metrics.JobsProcessed.WithLabelValues(stream, string(JobSucceeded)).Inc()
+}
+
+// invokeHandler calls handler.Handle and converts a panic into an error, so
+// a single bad handler can't take down the whole worker process or leave
+// jobs_inflight stuck incremented; process treats the result exactly like
+// any other failed attempt.
+func (p *Poller) invokeHandler(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return handler.Handle(ctx, job)
+}
+
+// ack acknowledges and removes a successfully processed message.
+func (p *Poller) ack(ctx context.Context, stream string, job *Job) {
+	if err := p.valkey.XAck(ctx, stream, consumerGroup, job.ID).Err(); err != nil {
+		slog.Error("ack: XACK failed", "stream", stream, "job_id", job.JobID, "error", err)
+	}
+	if err := p.valkey.XDel(ctx, stream, job.ID).Err(); err != nil {
+		slog.Error("ack: XDEL failed", "stream", stream, "job_id", job.JobID, "error", err)
+	}
+}
+
+// retryOrDeadLetter either reschedules job with exponential backoff or
+// moves it to the DLQ once job.Attempt reaches maxAttempts, and only then acks the
+// original delivery. The retry/DLQ write must land first: if the process
+// crashed between ack and that write, the job would be gone from the PEL
+// without ever having been persisted anywhere else.
+func (p *Poller) retryOrDeadLetter(ctx context.Context, stream string, job *Job, cause error) {
+	if job.Attempt >= maxAttempts {
+		slog.Warn("process: max attempts exceeded, sending to DLQ", "stream", stream, "job_id", job.JobID, "attempt", job.Attempt, "error", cause)
+		p.deadLetter(ctx, stream, job, cause.Error())
+		p.ack(ctx, stream, job)
+		return
+	}
+
+	job.Attempt++
+	delay := baseBackoff * time.Duration(1<<uint(job.Attempt-1))
+	if err := scheduleRetry(ctx, p.valkey, job, delay); err != nil {
+		slog.Error("process: failed to schedule retry, sending to DLQ", "stream", stream, "job_id", job.JobID, "error", err)
+		p.deadLetter(ctx, stream, job, cause.Error())
+		p.ack(ctx, stream, job)
+		return
+	}
+
+	slog.Info("process: scheduled retry", "stream", stream, "job_id", job.JobID, "attempt", job.Attempt, "delay", delay)
+	p.store.Upsert(ctx, job, JobFailed, cause.Error())
+	p.ack(ctx, stream, job)
+}
+
+// deadLetter moves job onto its stream's DLQ (<stream>:dead) for manual
+// inspection or replay.
+func (p *Poller) deadLetter(ctx context.Context, stream string, job *Job, cause string) {
+	fields := map[string]interface{}{"type": job.Type, "attempt": job.Attempt, "job_id": job.JobID, "error": cause}
+	if job.RequestID != "" {
+		fields["request_id"] = job.RequestID
+	}
+	for k, v := range job.Payload {
+		fields[k] = v
+	}
+	deadStream := stream + deadSuffix
+	if err := p.valkey.XAdd(ctx, &redis.XAddArgs{Stream: deadStream, Values: fields}).Err(); err != nil {
+		slog.Error("deadLetter: XADD failed", "stream", deadStream, "job_id", job.JobID, "error", err)
+	}
+	p.store.Upsert(ctx, job, JobFailed, cause)
+}
+
+// Enqueue publishes a new job of jobType onto stream via XADD and records
+// its initial queued state in app.jobs via store. Callers (e.g. API
+// handlers) use this to submit work; if ctx carries a request ID it is
+// attached to the job so downstream worker logs can be correlated back to
+// the originating request. The returned string is the job's stable logical
+// ID (distinct from the Valkey stream entry ID, which changes on every
+// retry), suitable for looking up status via store.
+func Enqueue(ctx context.Context, valkey *redis.Client, store *Store, stream, jobType string, payload map[string]interface{}) (string, error) {
+	jobID := uuid.NewString()
+	reqID := evilog.RequestIDFromContext(ctx)
+
+	fields := map[string]interface{}{"type": jobType, "attempt": 1, "job_id": jobID}
+	if reqID != "" {
+		fields["request_id"] = reqID
+	}
+	for k, v := range payload {
+		fields[k] = v
+	}
+	if _, err := valkey.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: fields}).Result(); err != nil {
+		return "", fmt.Errorf("enqueue job: %w", err)
+	}
+
+	store.Upsert(ctx, &Job{JobID: jobID, Stream: stream, Type: jobType, Attempt: 1, RequestID: reqID}, JobQueued, "")
+	return jobID, nil
+}
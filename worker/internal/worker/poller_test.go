@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeExecDB is a no-op dbHandle: retryOrDeadLetter's job-status writes are
+// logged rather than propagated as errors, so these tests don't need a
+// working app.jobs table, just something that doesn't panic.
+type fakeExecDB struct{}
+
+func (fakeExecDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func newTestPoller(t *testing.T) *Poller {
+	t.Helper()
+	return &Poller{
+		valkey:   newTestValkey(t),
+		store:    &Store{db: fakeExecDB{}},
+		consumer: "test-consumer",
+	}
+}
+
+// TestRetryOrDeadLetter_SchedulesRetryUnderMaxAttempts verifies a job below
+// maxAttempts is scheduled for backoff redelivery, with Attempt
+// incremented, rather than sent to the DLQ.
+func TestRetryOrDeadLetter_SchedulesRetryUnderMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPoller(t)
+
+	job := &Job{ID: "1-1", Stream: "orders", Type: "ship", JobID: "job-1", Attempt: maxAttempts - 1}
+	p.retryOrDeadLetter(ctx, "orders", job, errFake)
+
+	if job.Attempt != maxAttempts {
+		t.Fatalf("Attempt: want %d, got %d", maxAttempts, job.Attempt)
+	}
+
+	delayedCount, err := p.valkey.ZCard(ctx, delayedSetKey).Result()
+	if err != nil {
+		t.Fatalf("ZCARD: %v", err)
+	}
+	if delayedCount != 1 {
+		t.Fatalf("delayed set: want 1 scheduled retry, got %d", delayedCount)
+	}
+
+	deadMsgs, err := p.valkey.XRange(ctx, "orders"+deadSuffix, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRANGE dead stream: %v", err)
+	}
+	if len(deadMsgs) != 0 {
+		t.Fatalf("dead stream: want no entries below maxAttempts, got %d", len(deadMsgs))
+	}
+}
+
+// TestRetryOrDeadLetter_DeadLettersAtMaxAttempts verifies a job that has
+// already reached maxAttempts is sent to the DLQ instead of scheduled for
+// yet another retry.
+func TestRetryOrDeadLetter_DeadLettersAtMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPoller(t)
+
+	job := &Job{ID: "1-1", Stream: "orders", Type: "ship", JobID: "job-2", Attempt: maxAttempts}
+	p.retryOrDeadLetter(ctx, "orders", job, errFake)
+
+	delayedCount, err := p.valkey.ZCard(ctx, delayedSetKey).Result()
+	if err != nil {
+		t.Fatalf("ZCARD: %v", err)
+	}
+	if delayedCount != 0 {
+		t.Fatalf("delayed set: want no retry scheduled at maxAttempts, got %d entries", delayedCount)
+	}
+
+	deadMsgs, err := p.valkey.XRange(ctx, "orders"+deadSuffix, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRANGE dead stream: %v", err)
+	}
+	if len(deadMsgs) != 1 {
+		t.Fatalf("dead stream: want 1 dead-lettered entry, got %d", len(deadMsgs))
+	}
+	if deadMsgs[0].Values["job_id"] != "job-2" {
+		t.Fatalf("dead-lettered entry: want job_id job-2, got %v", deadMsgs[0].Values["job_id"])
+	}
+}
+
+type fakeErr string
+
+func (e fakeErr) Error() string { return string(e) }
+
+const errFake = fakeErr("handler failed")
@@ -0,0 +1,115 @@
+// Version: 1.0.0
+// Purpose: Idle-claim reaper. Periodically scans each stream's pending
+//          entries list via XPENDING and claims messages idle past a
+//          threshold via XCLAIM, recovering jobs from crashed consumers.
+// Backend file: reaper.go
+
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// claimReaper steals abandoned pending entries and redelivers them.
+type claimReaper struct {
+	valkey    *redis.Client
+	group     string
+	consumer  string
+	minIdle   time.Duration
+	interval  time.Duration
+	streams   []string
+	deliverFn func(ctx context.Context, stream string, msg redis.XMessage, attempt int)
+}
+
+func newClaimReaper(valkey *redis.Client, group, consumer string, streams []string, minIdle, interval time.Duration, deliverFn func(context.Context, string, redis.XMessage, int)) *claimReaper {
+	return &claimReaper{
+		valkey:    valkey,
+		group:     group,
+		consumer:  consumer,
+		minIdle:   minIdle,
+		interval:  interval,
+		streams:   streams,
+		deliverFn: deliverFn,
+	}
+}
+
+func (r *claimReaper) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, stream := range r.streams {
+				r.reapStream(ctx, stream)
+			}
+		}
+	}
+}
+
+// reapStream claims and redelivers entries on stream that have been
+// pending for longer than minIdle, which usually means their original
+// consumer crashed before acking them.
+func (r *claimReaper) reapStream(ctx context.Context, stream string) {
+	pending, err := r.valkey.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  r.group,
+		Start:  "-",
+		End:    "+",
+		Count:  100,
+		Idle:   r.minIdle,
+	}).Result()
+	if err != nil {
+		slog.Error("claim reaper: XPENDING failed", "stream", stream, "error", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(pending))
+	// deliveryCounts carries Valkey's own per-entry delivery count (bumped
+	// on every XREADGROUP/XCLAIM delivery, tracked in the PEL itself) so
+	// it survives a consumer that crashed without ever persisting
+	// anything of its own. deliverFn uses it in place of the attempt
+	// count on the stale stream entry when it is the larger of the two.
+	deliveryCounts := make(map[string]int, len(pending))
+	for _, p := range pending {
+		// A pending entry still owned by this same consumer name isn't
+		// abandoned - it's just a handler in this process that has been
+		// running longer than minIdle. Reclaiming it would hand the same
+		// message to a second goroutine in this process while the first
+		// is still working it.
+		if p.Consumer == r.consumer {
+			continue
+		}
+		ids = append(ids, p.ID)
+		deliveryCounts[p.ID] = int(p.RetryCount)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	claimed, err := r.valkey.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   stream,
+		Group:    r.group,
+		Consumer: r.consumer,
+		MinIdle:  r.minIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		slog.Error("claim reaper: XCLAIM failed", "stream", stream, "error", err)
+		return
+	}
+
+	for _, msg := range claimed {
+		slog.Warn("claim reaper: reclaimed abandoned message", "stream", stream, "id", msg.ID)
+		r.deliverFn(ctx, stream, msg, deliveryCounts[msg.ID])
+	}
+}
@@ -0,0 +1,51 @@
+// Version: 1.0.0
+// Purpose: Registry mapping a stream and job type to the Handler that
+//          processes it. Populated by callers before Poller.Run starts.
+// Backend file: registry.go
+
+package worker
+
+import "fmt"
+
+// Registry maps a stream to the handlers registered for job types within it.
+type Registry struct {
+	streams map[string]map[string]Handler
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{streams: make(map[string]map[string]Handler)}
+}
+
+// Register associates a job type within a stream with a Handler. The first
+// registration for a stream also makes it known to the poller, which
+// bootstraps a consumer group for it on startup.
+func (r *Registry) Register(stream, jobType string, h Handler) {
+	types, ok := r.streams[stream]
+	if !ok {
+		types = make(map[string]Handler)
+		r.streams[stream] = types
+	}
+	types[jobType] = h
+}
+
+// Streams returns the streams that have at least one handler registered.
+func (r *Registry) Streams() []string {
+	streams := make([]string, 0, len(r.streams))
+	for s := range r.streams {
+		streams = append(streams, s)
+	}
+	return streams
+}
+
+func (r *Registry) lookup(stream, jobType string) (Handler, error) {
+	types, ok := r.streams[stream]
+	if !ok {
+		return nil, fmt.Errorf("no handlers registered for stream %q", stream)
+	}
+	h, ok := types[jobType]
+	if !ok {
+		return nil, fmt.Errorf("no handler registered for job type %q on stream %q", jobType, stream)
+	}
+	return h, nil
+}
@@ -0,0 +1,63 @@
+// Version: 1.0.0
+// Purpose: Persists job status transitions to app.jobs so the API backend
+//          can surface job state to clients.
+// Backend file: store.go
+
+package worker
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// JobStatus is the lifecycle state of a job recorded in app.jobs.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// dbHandle is the one *pgxpool.Pool method Store actually calls, narrowed
+// to an interface so tests can exercise poller/reaper logic against a fake
+// instead of a live Postgres.
+type dbHandle interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Store persists job status transitions to PostgreSQL.
+type Store struct {
+	db dbHandle
+}
+
+// NewStore creates a Store backed by the given connection pool.
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{db: db}
+}
+
+// Upsert records a status transition for job, creating the row on first
+// write and updating it on subsequent calls. job.JobID (the stable logical
+// ID, not the Valkey stream entry ID) is the conflict key, so every retry
+// of the same logical job updates one row instead of creating a new one.
+// Failures are logged rather than returned since status tracking must
+// never block job processing.
+func (s *Store) Upsert(ctx context.Context, job *Job, status JobStatus, lastErr string) {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO app.jobs (job_id, stream, job_type, status, attempt, last_error, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), now())
+		ON CONFLICT (job_id) DO UPDATE
+		SET status = EXCLUDED.status,
+		    attempt = EXCLUDED.attempt,
+		    last_error = EXCLUDED.last_error,
+		    updated_at = now()`,
+		job.JobID, job.Stream, job.Type, status, job.Attempt, lastErr,
+	)
+	if err != nil {
+		slog.Error("failed to persist job status", "job_id", job.JobID, "request_id", job.RequestID, "status", string(status), "error", err)
+	}
+}